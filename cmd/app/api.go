@@ -0,0 +1,332 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/export"
+	"TwitchDonoCalculator/internal/validator"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeAPI runs the JSON API on cfg.HTTP.Addr until ctx is done. It returns
+// right away when no address is configured.
+func (app *application) ServeAPI(ctx context.Context) error {
+	if app.cfg.HTTP.Addr == "" {
+		return nil
+	}
+	srv := &http.Server{
+		Addr:         app.cfg.HTTP.Addr,
+		Handler:      app.apiRoutes(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  time.Minute,
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	}
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	}()
+
+	app.logger.Info("starting API server", "addr", srv.Addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-shutdownErr
+}
+
+func (app *application) apiRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/donations", app.apiListDonations)
+	mux.HandleFunc("GET /api/streamers", app.apiListStreamers)
+	mux.HandleFunc("GET /api/stats", app.apiGetStats)
+	mux.HandleFunc("GET /api/leaderboard", app.apiGetLeaderboard)
+	mux.HandleFunc("GET /api/feed/{channel}", app.apiFeed)
+	mux.HandleFunc("GET /overlay/{channel}", app.serveOverlay)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		app.apiError(w, http.StatusNotFound, "not found")
+	})
+
+	root := http.NewServeMux()
+	root.Handle("/", app.apiRequireKey(mux))
+	// Discord signs interactions instead of sending the API key
+	if bot, ok := app.discord.(*discord.Bot); ok {
+		root.Handle("POST /discord/interactions", bot)
+	}
+	return app.apiRecoverPanic(root)
+}
+
+// apiRequireKey accepts the key as "Authorization: Bearer <key>", in the
+// X-API-Key header or, for OBS browser sources that can't set headers, as the
+// key query parameter. The overlay key only opens the overlay and the feed.
+func (app *application) apiRequireKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if header := r.Header.Get("X-API-Key"); header != "" {
+			key = header
+		}
+		if auth, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+			key = auth
+		}
+		if !app.validKey(key, r.URL.Path) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.apiError(w, http.StatusUnauthorized, "invalid or missing API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validKey reports whether key opens urlPath.
+func (app *application) validKey(key, urlPath string) bool {
+	if key == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(app.cfg.HTTP.APIKey)) == 1 {
+		return true
+	}
+	overlayKey := app.cfg.HTTP.OverlayKey
+	if overlayKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(overlayKey)) != 1 {
+		return false
+	}
+	urlPath = path.Clean(urlPath)
+	return strings.HasPrefix(urlPath, "/overlay/") || strings.HasPrefix(urlPath, "/api/feed/")
+}
+
+func (app *application) apiRecoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				w.Header().Set("Connection", "close")
+				app.apiServerError(w, r, fmt.Errorf("%v", err))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+type apiErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+	Errors []string          `json:"errors,omitempty"`
+}
+
+func (app *application) writeJSON(w http.ResponseWriter, status int, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		app.logger.Error("failed to encode response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+func (app *application) apiError(w http.ResponseWriter, status int, message string) {
+	app.writeJSON(w, status, apiErrorResponse{Error: message})
+}
+
+// apiValidationError reports the field errors of v the same way the Discord
+// commands do, keyed by query parameter.
+func (app *application) apiValidationError(w http.ResponseWriter, v *validator.Validator) {
+	app.writeJSON(w, http.StatusUnprocessableEntity, apiErrorResponse{
+		Error:  "invalid parameters",
+		Fields: v.FieldErrors,
+		Errors: v.NonFieldErrors,
+	})
+}
+
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Error("API request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	app.apiError(w, http.StatusInternalServerError, "internal server error")
+}
+
+// queryInt64 reads an integer query parameter, reporting a field error when
+// it is not a number.
+func queryInt64(v *validator.Validator, query url.Values, key string, defaultValue int64) int64 {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	v.CheckField(err == nil, key, strings.ToUpper(key[:1])+key[1:]+" must be an integer.")
+	return n
+}
+
+func queryString(query url.Values, key, defaultValue string) string {
+	if value := query.Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+type apiDonationsResponse struct {
+	Donations []export.Row `json:"donations"`
+	Next      string       `json:"next,omitempty"`
+}
+
+func (app *application) apiListDonations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	argsStruct := &DiscordGetLastDonationsArgs{}
+	minAmount := queryInt64(&argsStruct.Validator, query, "min", 0)
+	limit := queryInt64(&argsStruct.Validator, query, "limit", 10)
+	app.parseLastDonationsArgs(argsStruct, query.Get("channel"), query.Get("donor"), minAmount, limit, query.Get("since"), query.Get("page"))
+	if !argsStruct.Valid() {
+		app.apiValidationError(w, &argsStruct.Validator)
+		return
+	}
+
+	res, hasMore, err := app.listLastDonations(r.Context(), argsStruct)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	resp := apiDonationsResponse{Donations: make([]export.Row, len(res))}
+	for i, d := range res {
+		resp.Donations[i] = export.NewRow(d)
+	}
+	if hasMore {
+		last := res[len(res)-1]
+		resp.Next = encodePageToken(last.Timestamp, last.ID)
+	}
+	app.writeJSON(w, http.StatusOK, resp)
+}
+
+type apiStreamer struct {
+	Channel           string    `json:"channel"`
+	BotName           string    `json:"botName"`
+	ValueRegex        string    `json:"valueRegex"`
+	LineFilterContain string    `json:"lineFilterContain"`
+	LogMessage        bool      `json:"logMessage"`
+	NotifyThreshold   int64     `json:"notifyThreshold"`
+	Enabled           bool      `json:"enabled"`
+	UpdatedBy         string    `json:"updatedBy"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+func (app *application) apiListStreamers(w http.ResponseWriter, r *http.Request) {
+	rows, err := app.db.ListStreamers(r.Context())
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	streamers := make([]apiStreamer, len(rows))
+	for i, s := range rows {
+		streamers[i] = apiStreamer{
+			Channel:           s.Channel,
+			BotName:           s.BotName,
+			ValueRegex:        s.ValueRegex,
+			LineFilterContain: s.LineFilterContain,
+			LogMessage:        s.LogMessage,
+			NotifyThreshold:   s.NotifyThreshold,
+			Enabled:           s.Enabled,
+			UpdatedBy:         s.UpdatedBy,
+			UpdatedAt:         s.UpdatedAt.UTC(),
+		}
+	}
+	app.writeJSON(w, http.StatusOK, map[string]any{"streamers": streamers})
+}
+
+type apiBucket struct {
+	Start  time.Time `json:"start"`
+	Count  int64     `json:"count"`
+	Sum    int64     `json:"sum"`
+	Avg    float64   `json:"avg"`
+	Median float64   `json:"median"`
+	Max    int64     `json:"max"`
+}
+
+type apiChannelStats struct {
+	Channel string      `json:"channel"`
+	Buckets []apiBucket `json:"buckets"`
+}
+
+type apiStatsResponse struct {
+	Interval string            `json:"interval"`
+	Timezone string            `json:"timezone"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Channels []apiChannelStats `json:"channels"`
+}
+
+func (app *application) apiGetStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	argsStruct := app.parseStatsArgs(query.Get("channel"), queryString(query, "by", "day"), queryString(query, "tz", app.cfg.Timezone), query.Get("from"), query.Get("to"))
+	if !argsStruct.Valid() {
+		app.apiValidationError(w, &argsStruct.Validator)
+		return
+	}
+
+	res, err := app.loadStats(r.Context(), argsStruct)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	resp := apiStatsResponse{
+		Interval: string(argsStruct.Interval),
+		Timezone: argsStruct.Location.String(),
+		From:     argsStruct.From,
+		To:       argsStruct.To,
+		Channels: make([]apiChannelStats, len(res)),
+	}
+	for i, cs := range res {
+		buckets := make([]apiBucket, len(cs.Buckets))
+		for j, b := range cs.Buckets {
+			buckets[j] = apiBucket(b)
+		}
+		resp.Channels[i] = apiChannelStats{Channel: cs.Channel, Buckets: buckets}
+	}
+	app.writeJSON(w, http.StatusOK, resp)
+}
+
+type apiDonor struct {
+	Rank      int    `json:"rank"`
+	Donor     string `json:"donor"`
+	Total     int64  `json:"total"`
+	Donations int64  `json:"donations"`
+	Largest   int64  `json:"largest"`
+}
+
+func (app *application) apiGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	argsStruct := &DiscordGetTopDonorsArgs{}
+	limit := queryInt64(&argsStruct.Validator, query, "limit", 10)
+	app.parseTopDonorsArgs(argsStruct, query.Get("channel"), queryString(query, "by", "total"), limit, query.Get("from"), query.Get("to"))
+	if !argsStruct.Valid() {
+		app.apiValidationError(w, &argsStruct.Validator)
+		return
+	}
+
+	res, err := app.db.ListTopDonors(r.Context(), argsStruct.params())
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	donors := make([]apiDonor, len(res))
+	for i, d := range res {
+		donors[i] = apiDonor{
+			Rank:      i + 1,
+			Donor:     d.Donor,
+			Total:     d.Total,
+			Donations: d.Donations,
+			Largest:   d.Largest,
+		}
+	}
+	app.writeJSON(w, http.StatusOK, map[string]any{"donors": donors})
+}
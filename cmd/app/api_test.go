@@ -0,0 +1,261 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	testAPIKey     = "0123456789abcdef"
+	testOverlayKey = "overlay-0123456789"
+)
+
+// newTestAPI returns a test server for the API of an app with two donations
+// in #tartancz.
+func newTestAPI(t *testing.T) (*application, *httptest.Server) {
+	t.Helper()
+	app, _ := newTestApp(t)
+	app.cfg.HTTP.APIKey = testAPIKey
+	ctx := context.Background()
+	if _, err := app.SaveStreamer(ctx, "#tartancz", config.StreamerConfig{BotName: "bot", ValueRegex: `\d+`}, configChangedBy); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []struct {
+		donor  string
+		amount int64
+	}{{"alice", 100}, {"bob", 700}} {
+		app.saveDonation(db.CreateDonationParams{User: "bot", Channel: "#tartancz", SendFrom: d.donor, Amount: d.amount, Text: d.donor})
+	}
+	srv := httptest.NewServer(app.apiRoutes())
+	t.Cleanup(srv.Close)
+	return app, srv
+}
+
+// getJSON requests path with the API key and decodes the response into v.
+func getJSON(t *testing.T, srv *httptest.Server, path string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: Content-Type %q", path, ct)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return res.StatusCode
+}
+
+func TestAPIRequireKey(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.cfg.HTTP.APIKey = testAPIKey
+	app.cfg.HTTP.OverlayKey = testOverlayKey
+	handler := app.apiRequireKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		target string
+		header [2]string
+		want   int
+	}{
+		{"missing", "/api/streamers", [2]string{}, http.StatusUnauthorized},
+		{"wrong bearer", "/api/streamers", [2]string{"Authorization", "Bearer wrong"}, http.StatusUnauthorized},
+		{"bearer", "/api/streamers", [2]string{"Authorization", "Bearer " + testAPIKey}, http.StatusNoContent},
+		{"basic", "/api/streamers", [2]string{"Authorization", "Basic " + testAPIKey}, http.StatusUnauthorized},
+		{"x-api-key", "/api/streamers", [2]string{"X-API-Key", testAPIKey}, http.StatusNoContent},
+		{"wrong x-api-key", "/api/streamers?key=" + testAPIKey, [2]string{"X-API-Key", "wrong"}, http.StatusUnauthorized},
+		{"query", "/api/streamers?key=" + testAPIKey, [2]string{}, http.StatusNoContent},
+		{"wrong query", "/api/streamers?key=wrong", [2]string{}, http.StatusUnauthorized},
+		{"api key on the overlay", "/overlay/tartancz?key=" + testAPIKey, [2]string{}, http.StatusNoContent},
+		{"overlay key on the overlay", "/overlay/tartancz?key=" + testOverlayKey, [2]string{}, http.StatusNoContent},
+		{"overlay key on the feed", "/api/feed/tartancz?key=" + testOverlayKey, [2]string{}, http.StatusNoContent},
+		{"overlay key on donations", "/api/donations?key=" + testOverlayKey, [2]string{}, http.StatusUnauthorized},
+		{"overlay key bearer", "/api/streamers", [2]string{"Authorization", "Bearer " + testOverlayKey}, http.StatusUnauthorized},
+		{"overlay key escaping the feed", "/api/feed/../donations?key=" + testOverlayKey, [2]string{}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header[0] != "" {
+				req.Header.Set(tt.header[0], tt.header[1])
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Fatalf("status %d, want %d", rr.Code, tt.want)
+			}
+			if rr.Code == http.StatusUnauthorized {
+				var body apiErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Error == "" {
+					t.Errorf("body %q is not an API error: %v", rr.Body, err)
+				}
+				if rr.Header().Get("WWW-Authenticate") != "Bearer" {
+					t.Error("missing WWW-Authenticate header")
+				}
+			}
+		})
+	}
+}
+
+func TestAPIValidationError(t *testing.T) {
+	_, srv := newTestAPI(t)
+	var body apiErrorResponse
+	status := getJSON(t, srv, "/api/donations?limit=abc&since=yesterday-ish", &body)
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", status)
+	}
+	if body.Error != "invalid parameters" {
+		t.Errorf("error %q", body.Error)
+	}
+	// since is reported like the from flag of the Discord command
+	for _, field := range []string{"limit", "from"} {
+		if body.Fields[field] == "" {
+			t.Errorf("no error for %s in %v", field, body.Fields)
+		}
+	}
+
+	body = apiErrorResponse{}
+	if status := getJSON(t, srv, "/api/stats?by=fortnight&tz=Nowhere/Else", &body); status != http.StatusUnprocessableEntity {
+		t.Fatalf("stats status %d, want 422", status)
+	}
+	if body.Fields["by"] == "" || body.Fields["tz"] == "" {
+		t.Errorf("stats fields %v", body.Fields)
+	}
+}
+
+func TestAPIRoutes(t *testing.T) {
+	_, srv := newTestAPI(t)
+
+	var donations apiDonationsResponse
+	if status := getJSON(t, srv, "/api/donations?channel=tartancz&limit=1", &donations); status != http.StatusOK {
+		t.Fatalf("donations status %d", status)
+	}
+	if len(donations.Donations) != 1 || donations.Next == "" {
+		t.Fatalf("first page %+v, want one donation and a next token", donations)
+	}
+	var next apiDonationsResponse
+	getJSON(t, srv, "/api/donations?channel=tartancz&limit=1&page="+donations.Next, &next)
+	if len(next.Donations) != 1 || next.Next != "" || next.Donations[0].Donor == donations.Donations[0].Donor {
+		t.Errorf("second page %+v after %+v", next, donations)
+	}
+
+	var streamers struct{ Streamers []apiStreamer }
+	if status := getJSON(t, srv, "/api/streamers", &streamers); status != http.StatusOK {
+		t.Fatalf("streamers status %d", status)
+	}
+	if len(streamers.Streamers) != 1 || streamers.Streamers[0].Channel != "#tartancz" || !streamers.Streamers[0].Enabled {
+		t.Errorf("streamers %+v", streamers.Streamers)
+	}
+
+	var stats apiStatsResponse
+	if status := getJSON(t, srv, "/api/stats?channel=tartancz&by=day&from=today", &stats); status != http.StatusOK {
+		t.Fatalf("stats status %d", status)
+	}
+	if len(stats.Channels) != 1 || len(stats.Channels[0].Buckets) == 0 {
+		t.Fatalf("stats %+v", stats)
+	}
+	var sum int64
+	for _, b := range stats.Channels[0].Buckets {
+		sum += b.Sum
+	}
+	if sum != 800 || stats.Interval != "day" {
+		t.Errorf("stats sum %d interval %q, want 800 by day", sum, stats.Interval)
+	}
+
+	var leaderboard struct{ Donors []apiDonor }
+	if status := getJSON(t, srv, "/api/leaderboard?channel=tartancz", &leaderboard); status != http.StatusOK {
+		t.Fatalf("leaderboard status %d", status)
+	}
+	if len(leaderboard.Donors) != 2 || leaderboard.Donors[0].Donor != "bob" || leaderboard.Donors[0].Rank != 1 || leaderboard.Donors[0].Total != 700 {
+		t.Errorf("leaderboard %+v", leaderboard.Donors)
+	}
+
+	var notFound apiErrorResponse
+	if status := getJSON(t, srv, "/api/nothing", &notFound); status != http.StatusNotFound || notFound.Error != "not found" {
+		t.Errorf("unknown route: %d %+v", status, notFound)
+	}
+}
+
+func TestAPIStatsLimitsBuckets(t *testing.T) {
+	_, srv := newTestAPI(t)
+	var body apiErrorResponse
+	if status := getJSON(t, srv, "/api/stats?by=hour&from=0001-01-01", &body); status != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", status)
+	}
+	if body.Fields["from"] == "" {
+		t.Errorf("fields %v, want an error for from", body.Fields)
+	}
+}
+
+func TestAPIFeed(t *testing.T) {
+	app, srv := newTestAPI(t)
+
+	var invalid apiErrorResponse
+	if status := getJSON(t, srv, "/api/feed/..%2F..%2Fetc", &invalid); status != http.StatusUnprocessableEntity || invalid.Fields["channel"] == "" {
+		t.Errorf("invalid channel: %d %+v", status, invalid)
+	}
+
+	// the first donation was saved first and has the lower id
+	rows, err := app.loadFeed(context.Background(), "#tartancz", 10)
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("loadFeed = %v, %v", rows, err)
+	}
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/feed/tartancz?key="+testAPIKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(rows[0].ID, 10))
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); res.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("status %d, Content-Type %q", res.StatusCode, ct)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	next := func() []string {
+		var event []string
+		for scanner.Scan() {
+			if scanner.Text() == "" {
+				return event
+			}
+			event = append(event, scanner.Text())
+		}
+		t.Fatalf("feed ended: %v", scanner.Err())
+		return nil
+	}
+	if event := next(); len(event) != 3 || event[0] != fmt.Sprintf("id: %d", rows[1].ID) || event[1] != "event: replay" || !strings.Contains(event[2], `"bob"`) {
+		t.Errorf("first event %q, want the replay of bob only", event)
+	}
+	if event := next(); len(event) != 2 || event[0] != "event: state" {
+		t.Errorf("second event %q, want the state", event)
+	}
+
+	app.saveDonation(db.CreateDonationParams{User: "bot", Channel: "#tartancz", SendFrom: "carol", Amount: 300, Text: "carol"})
+	if event := next(); len(event) != 3 || event[1] != "event: donation" || !strings.Contains(event[2], `"carol"`) {
+		t.Errorf("live event %q, want carol's donation", event)
+	}
+	if event := next(); len(event) != 2 || event[0] != "event: state" || !strings.Contains(event[1], `"bob"`) {
+		t.Errorf("live state %q, want bob on top", event)
+	}
+}
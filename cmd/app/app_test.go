@@ -0,0 +1,51 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/feed"
+	"TwitchDonoCalculator/internal/health"
+	"TwitchDonoCalculator/internal/session"
+	"TwitchDonoCalculator/internal/twitch"
+	"TwitchDonoCalculator/internal/webhook"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestApp returns an application wired like run, backed by a migrated
+// SQLite database in a temporary directory and a discord.Recorder, with the
+// Discord commands registered.
+func newTestApp(t *testing.T) (*application, *discord.Recorder) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DB.DSN = filepath.Join(t.TempDir(), "db.db")
+	database, err := db.OpenDB(cfg.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	db.RunMigrations(database)
+
+	recorder := discord.NewRecorder()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &application{
+		db:        db.New(database),
+		database:  database,
+		twitch:    twitch.NewAnonymousClient(),
+		discord:   recorder,
+		sessions:  session.NewTracker(db.New(database), time.Duration(cfg.Session.Gap), nil),
+		health:    health.NewMonitor(cfg.Health, recorder.Alerts(discord.OpsChannel), logger),
+		streamers: map[string]*Streamer{},
+		cfg:       cfg,
+		logger:    logger,
+	}
+	app.feed = feed.NewHub(cfg.HTTP.FeedReplay, app.loadFeed)
+	app.webhooks = webhook.NewOutbox(app.db, nil, &http.Client{}, app.webhookDead)
+	app.registerDiscordCommands()
+	return app, recorder
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/twitch"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// runConfig implements the "config" command family used to check a config
+// before deploying it.
+func runConfig(configPath string, args []string) error {
+	usage := errors.New("usage: config <validate|test-regex|dump> [flags]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(configPath, os.Stdout)
+	case "test-regex":
+		return runConfigTestRegex(configPath, args[1:], os.Stdin, os.Stdout)
+	case "dump":
+		return runConfigDump(configPath, os.Stdout)
+	default:
+		return fmt.Errorf("unknown config command: %s\n%w", args[0], usage)
+	}
+}
+
+func runConfigValidate(configPath string, out io.Writer) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("config is invalid:\n%w", err)
+	}
+	configs, err := loadConfiguredStreamers(cfg)
+	if err != nil {
+		return err
+	}
+	streamers, err := NewStreamersFromMap(configs)
+	if err != nil {
+		return fmt.Errorf("config is invalid:\n%w", err)
+	}
+	fmt.Fprintf(out, "%s is valid, %d streamers configured.\n", cfg.Source, len(streamers))
+	return nil
+}
+
+// loadConfiguredStreamers returns the streamers run would track: the rows of
+// the database with the entries of the config file merged in, the same way
+// ImportConfigStreamers does on start. Disabled ones are included.
+func loadConfiguredStreamers(cfg *config.Config) (map[string]*config.StreamerConfig, error) {
+	database, err := db.OpenDB(cfg.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer database.Close()
+	db.RunMigrations(database)
+
+	rows, err := db.New(database).ListStreamers(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list streamers: %w", err)
+	}
+	streamers := make(map[string]*config.StreamerConfig, len(rows)+len(cfg.Streamers))
+	updatedBy := make(map[string]string, len(rows))
+	for _, row := range rows {
+		streamers[row.Channel] = streamerConfigFromDB(row)
+		updatedBy[row.Channel] = row.UpdatedBy
+	}
+	for channel, sc := range cfg.Streamers {
+		if by, exists := updatedBy[channel]; exists && by != configChangedBy {
+			continue
+		}
+		streamers[channel] = sc
+	}
+	return streamers, nil
+}
+
+func runConfigDump(configPath string, out io.Writer) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("config is invalid:\n%w", err)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	return enc.Encode(cfg.Redacted())
+}
+
+// runConfigTestRegex feeds sample lines through Streamer.FindDonation. Lines
+// can be plain chat text or raw IRC lines as written to the log folder, in
+// which case the sender is checked against the configured bot as well.
+func runConfigTestRegex(configPath string, args []string, stdin io.Reader, out io.Writer) error {
+	f := flag.NewFlagSet("config test-regex", flag.ContinueOnError)
+	channel := f.String("channel", "", "channel to test, e.g. #tartancz")
+	file := f.String("file", "", "read sample lines from a log file instead of stdin")
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if *channel == "" {
+		return errors.New("-channel is required")
+	}
+	name := normalizeChannel(*channel)
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("config is invalid:\n%w", err)
+	}
+	configs, err := loadConfiguredStreamers(cfg)
+	if err != nil {
+		return err
+	}
+	streamerConfig, exists := configs[name]
+	if !exists {
+		channels := slices.Sorted(maps.Keys(configs))
+		return fmt.Errorf("channel %s is not configured, known channels: %s", name, strings.Join(channels, ", "))
+	}
+	streamer, err := NewStreamer(*streamerConfig, name)
+	if err != nil {
+		return err
+	}
+
+	in := stdin
+	if *file != "" {
+		logFile, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+		in = logFile
+	}
+
+	var lines, matched int
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines++
+		text, sender := line, ""
+		switch m := twitch.ParseMessage(line).(type) {
+		case *twitch.MessagePrivate:
+			text, sender = m.Text, m.Sender
+		case *twitch.MessageNotice:
+			text = m.Text
+		}
+
+		switch value := streamer.FindDonation(text); {
+		case sender != "" && sender != streamer.BotName:
+			fmt.Fprintf(out, "skip\tsent by %s, not %s\t%s\n", sender, streamer.BotName, text)
+		case value == 0:
+			fmt.Fprintf(out, "none\t\t%s\n", text)
+		default:
+			matched++
+			fmt.Fprintf(out, "%d\t\t%s\n", value, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d of %d lines contain a donation.\n", matched, lines)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestConfig writes the config of app with the given file streamers and
+// returns its path.
+func writeTestConfig(t *testing.T, app *application, streamers map[string]*config.StreamerConfig) string {
+	t.Helper()
+	cfg := *app.cfg
+	cfg.Streamers = streamers
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := config.WriteFile(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigCommandsUseDatabaseStreamers(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	if _, err := app.SaveStreamer(ctx, "#added", config.StreamerConfig{BotName: "bot", ValueRegex: `\d+`}, discordChangedBy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SaveStreamer(ctx, "#edited", config.StreamerConfig{BotName: "bot", ValueRegex: `\d+`}, discordChangedBy); err != nil {
+		t.Fatal(err)
+	}
+	path := writeTestConfig(t, app, map[string]*config.StreamerConfig{
+		"#edited": {BotName: "bot", ValueRegex: `never`},
+		"#file":   {BotName: "bot", ValueRegex: `\d+`},
+	})
+
+	var out bytes.Buffer
+	if err := runConfigValidate(path, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "3 streamers configured") {
+		t.Errorf("validate output %q, want 3 streamers", out.String())
+	}
+
+	tests := []struct {
+		channel string
+		want    string
+	}{
+		{"added", "100\t\tsent 100"},
+		{"#edited", "100\t\tsent 100"},
+		{"file", "100\t\tsent 100"},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		if err := runConfigTestRegex(path, []string{"-channel", tt.channel}, strings.NewReader("sent 100\n"), &out); err != nil {
+			t.Errorf("%s: %v", tt.channel, err)
+			continue
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("%s: output %q, want %q", tt.channel, out.String(), tt.want)
+		}
+	}
+
+	err := runConfigTestRegex(path, []string{"-channel", "missing"}, strings.NewReader(""), &out)
+	if err == nil || !strings.Contains(err.Error(), "#added, #edited, #file") {
+		t.Errorf("missing channel error = %v, want the known channels", err)
+	}
+}
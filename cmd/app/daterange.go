@@ -0,0 +1,56 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/validator"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// lastStreamName is the relative range resolved from the most recent stream
+// session instead of the calendar.
+const lastStreamName = "last-stream"
+
+const (
+	dateFromUsage = "start date YYYY-MM-DD or a relative range: " + validator.RelativeRangeHelp + " or " + lastStreamName
+	dateToUsage   = "end date YYYY-MM-DD, inclusive"
+)
+
+var errNoStream = errors.New("no stream session recorded yet")
+
+// handleDateRange is validator.HandleDateRange in the reporting timezone that
+// also understands "last-stream" for channel (or any channel when empty).
+func (app *application) handleDateRange(v *validator.Validator, channel, from, to string, fromTime, toTime *time.Time) {
+	if !strings.EqualFold(from, lastStreamName) {
+		validator.HandleDateRange(v, from, to, app.cfg.Location, fromTime, toTime)
+		return
+	}
+	v.CheckField(to == "", "to", "To date can't be combined with "+lastStreamName+".")
+
+	start, end, err := app.lastStream(context.Background(), channel)
+	if err != nil {
+		v.AddFieldError("from", err.Error())
+		return
+	}
+	*fromTime = start
+	// timestamps are stored with second precision, so this includes end
+	*toTime = end.Add(time.Second)
+}
+
+// lastStream returns the bounds of the most recent stream session of channel,
+// the current time for one that is still open.
+func (app *application) lastStream(ctx context.Context, channel string) (time.Time, time.Time, error) {
+	s, err := app.db.GetLastStreamSession(ctx, channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, time.Time{}, errNoStream
+	}
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !s.EndedAt.Valid {
+		return s.StartedAt, time.Now(), nil
+	}
+	return s.StartedAt, s.EndedAt.Time, nil
+}
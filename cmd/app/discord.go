@@ -6,48 +6,85 @@ import (
 	"TwitchDonoCalculator/internal/validator"
 	"bytes"
 	"context"
-	"errors"
-	"flag"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 )
 
 func (app *application) registerDiscordCommands() {
-	discord.DefaultServer.AddHandler("donation", discord.DiscordMessageHandlerStruct{
-		HandleFunc:  app.DiscordGetAllDonationsByStreamer,
-		HelpMessage: "Get all donations by streamer within a date range.",
-	})
-}
+	app.discord.SetGuard(discordGuard{app: app})
+
+	app.discord.AddCommand(donationCommand, nil)
+	app.discord.AddCommand(donationSumCommand, app.DiscordGetAllDonationsByStreamer)
+	app.discord.AddCommand(topCommand, app.DiscordGetTopDonors)
+	app.discord.AddCommand(lastCommand, app.DiscordGetLastDonations)
+	app.discord.AddCommand(statsCommand, app.DiscordGetStats)
+	app.discord.AddCommand(sessionCommand, app.DiscordGetSessions)
+	app.discord.AddCommand(exportCommand, app.DiscordExport)
+
+	app.discord.AddCommand(goalCommand, nil)
+	app.discord.AddCommand(goalListCommand, app.discordGoalList)
+	app.discord.AddCommand(goalCreateCommand, app.discordGoalCreate)
+	app.discord.AddCommand(goalCloseCommand, app.discordGoalClose)
+
+	app.discord.AddCommand(streamerCommand, nil)
+	app.discord.AddCommand(streamerListCommand, app.discordStreamerList)
+	app.discord.AddCommand(streamerAddCommand, app.discordStreamerAdd)
+	app.discord.AddCommand(streamerEditCommand, app.discordStreamerEdit)
+	app.discord.AddCommand(streamerDisableCommand, app.discordStreamerDisable)
+	app.discord.AddCommand(streamerEnableCommand, app.discordStreamerEnable)
+	app.discord.AddCommand(streamerTestCommand, app.discordStreamerTest)
+	app.discord.AddCommand(streamerHistoryCommand, app.discordStreamerHistory)
+
+	app.discord.AddCommand(webhookCommand, nil)
+	app.discord.AddCommand(webhookListCommand, app.discordWebhookList)
+	app.discord.AddCommand(webhookDeliveriesCommand, app.discordWebhookDeliveries)
+	app.discord.AddCommand(webhookShowCommand, app.discordWebhookShow)
+	app.discord.AddCommand(webhookRedeliverCommand, app.discordWebhookRedeliver)
 
-func (app *application) newArgsParser(args discord.DiscordMessageArgs, writer io.Writer) *flag.FlagSet {
-	f := flag.NewFlagSet(args.CommandName, flag.ContinueOnError)
-	f.SetOutput(writer) // Suppress output to avoid cluttering the console
-	return f
+	app.discord.AddCommand(permissionCommand, nil)
+	app.discord.AddCommand(permissionListCommand, app.discordPermissionList)
+	app.discord.AddCommand(permissionGrantCommand, app.discordPermissionGrant)
+	app.discord.AddCommand(permissionRevokeCommand, app.discordPermissionRevoke)
+	app.discord.AddCommand(permissionAuditCommand, app.discordPermissionAudit)
 }
 
 type DiscordGetAllDonationsByStreamerArgs struct {
 	From time.Time
 	To   time.Time
+	Page int64
 	validator.Validator
 }
 
-func (app *application) DiscordGetAllDonationsByStreamer(args discord.DiscordMessageArgs, writer io.Writer) {
-	f := app.newArgsParser(args, writer)
+var donationCommand = discord.Command{
+	Name:    "donation",
+	Summary: "Sum, rank and list donations.",
+}
 
-	from := f.String("from", "", "start from date format: YYYY-MM-DD")
-	to := f.String("to", "", "end date format: YYYY-MM-DD")
+var donationSumCommand = discord.Command{
+	Name:    "donation sum",
+	Summary: "Get all donations by streamer within a date range.",
+	Flags: []discord.Flag{
+		{Name: "from", Usage: dateFromUsage},
+		{Name: "to", Usage: dateToUsage},
+		{Name: "page", Kind: discord.Int64Flag, Usage: "page token printed under the previous page"},
+	},
+}
 
-	if err := f.Parse(args.Args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			return
-		}
+func (app *application) DiscordGetAllDonationsByStreamer(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := donationSumCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
 	}
 
 	var argsStruct DiscordGetAllDonationsByStreamerArgs
 
-	validator.HandleDateRange(&argsStruct.Validator, *from, *to, &argsStruct.From, &argsStruct.To)
+	app.handleDateRange(&argsStruct.Validator, "", v.String("from"), v.String("to"), &argsStruct.From, &argsStruct.To)
+	argsStruct.Page = v.Int64("page")
+	argsStruct.CheckField(argsStruct.Page >= 0, "page", "Invalid page token.")
 
 	if !argsStruct.Valid() {
 		fmt.Fprintln(writer, argsStruct.Error())
@@ -64,6 +101,65 @@ func (app *application) DiscordGetAllDonationsByStreamer(args discord.DiscordMes
 		return
 	}
 	
+	if argsStruct.Page >= int64(len(res)) {
+		fmt.Fprintf(writer, "No donations found.\n")
+		return
+	}
+
+	table := discord.Table{
+		Header: "Channel\tAmount\tStartingDate\tEndingDate",
+		NextPage: func(next int) string {
+			return fmt.Sprintf("Next page: -page %d", argsStruct.Page+int64(next))
+		},
+	}
+	for _, r := range res[argsStruct.Page:] {
+		table.Rows = append(table.Rows, fmt.Sprintf("%s\t%d\t%s\t%s", r.Channel, r.Amount, app.formatDBDate(r.Startingdate), app.formatDBDate(r.Endingdate)))
+	}
+	fmt.Fprint(writer, table.Render())
+}
+
+type DiscordGetTopDonorsArgs struct {
+	Channel string
+	OrderBy string
+	Limit   int64
+	From    time.Time
+	To      time.Time
+	validator.Validator
+}
+
+var topCommand = discord.Command{
+	Name:    "donation top",
+	Aliases: []string{"top"},
+	Summary: "Get the top donors.",
+	Flags: []discord.Flag{
+		{Name: "channel", Usage: "only donations for this channel, all channels when empty"},
+		{Name: "by", Default: "total", Usage: "rank donors by total, count or largest"},
+		{Name: "limit", Kind: discord.Int64Flag, Default: "10", Usage: "number of donors (max 25)"},
+		{Name: "from", Usage: dateFromUsage},
+		{Name: "to", Usage: dateToUsage},
+	},
+}
+
+func (app *application) DiscordGetTopDonors(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := topCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+
+	argsStruct := &DiscordGetTopDonorsArgs{}
+	app.parseTopDonorsArgs(argsStruct, v.String("channel"), strings.ToLower(v.String("by")), v.Int64("limit"), v.String("from"), v.String("to"))
+
+	if !argsStruct.Valid() {
+		fmt.Fprintln(writer, argsStruct.Error())
+		return
+	}
+
+	res, err := app.db.ListTopDonors(context.Background(), argsStruct.params())
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting donors: %v\n", err)
+		return
+	}
+
 	if len(res) == 0 {
 		fmt.Fprintf(writer, "No donations found.\n")
 		return
@@ -73,17 +169,174 @@ func (app *application) DiscordGetAllDonationsByStreamer(args discord.DiscordMes
 
 	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
 
-	fmt.Fprintln(tb, "Channel\tAmount\tStartingDate\tEndingDate")
+	fmt.Fprintln(tb, "#\tDonor\tTotal\tCount\tLargest")
 
-	for _, r := range res {
-		fmt.Fprintf(tb, "%s\t%d\t%s\t%s\n", r.Channel, r.Amount, r.Startingdate, r.Endingdate)
+	for i, r := range res {
+		fmt.Fprintf(tb, "%d\t%s\t%d\t%d\t%d\n", i+1, r.Donor, r.Total, r.Donations, r.Largest)
 	}
 
 	tb.Flush()
 	fmt.Fprintf(writer, "```%s```", buf.String())
+}
+
+// parseTopDonorsArgs validates the leaderboard filters shared by the Discord
+// command and the HTTP API into argsStruct.
+func (app *application) parseTopDonorsArgs(argsStruct *DiscordGetTopDonorsArgs, channel, by string, limit int64, from, to string) {
+	if channel != "" {
+		argsStruct.Channel = normalizeChannel(channel)
+	}
+	argsStruct.OrderBy = by
+	argsStruct.Limit = limit
+	argsStruct.CheckField(by == "total" || by == "count" || by == "largest", "by", "By must be one of total, count or largest.")
+	argsStruct.CheckField(limit > 0 && limit <= 25, "limit", "Limit must be between 1 and 25.")
+	app.handleDateRange(&argsStruct.Validator, argsStruct.Channel, from, to, &argsStruct.From, &argsStruct.To)
+}
+
+func (a *DiscordGetTopDonorsArgs) params() db.ListTopDonorsParams {
+	return db.ListTopDonorsParams{
+		Channel:       a.Channel,
+		FromTimestamp: a.From,
+		ToTimestamp:   a.To,
+		OrderBy:       a.OrderBy,
+		RowLimit:      a.Limit,
+	}
+}
 
+type DiscordGetLastDonationsArgs struct {
+	Channel   string
+	Donor     string
+	MinAmount int64
+	Limit     int64
+	Since     time.Time
+	Until     time.Time
+	CursorTS  time.Time
+	CursorID  int64
+	validator.Validator
+}
+
+var lastCommand = discord.Command{
+	Name:    "donation last",
+	Aliases: []string{"last"},
+	Summary: "Get the most recent donations.",
+	Flags: []discord.Flag{
+		{Name: "channel", Usage: "only donations for this channel"},
+		{Name: "limit", Kind: discord.Int64Flag, Default: "10", Usage: "number of donations per page (max 50)"},
+		{Name: "donor", Usage: "only donations sent by this donor"},
+		{Name: "min", Kind: discord.Int64Flag, Usage: "only donations of at least this amount"},
+		{Name: "since", Usage: "only donations since " + dateFromUsage},
+		{Name: "page", Usage: "page token printed under the previous page"},
+	},
 }
 
-func (app *application) getLastDonationsFromStreamer(args discord.DiscordMessageArgs, writer io.Writer) {
+func (app *application) DiscordGetLastDonations(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := lastCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+
+	argsStruct := &DiscordGetLastDonationsArgs{}
+	app.parseLastDonationsArgs(argsStruct, v.String("channel"), v.String("donor"), v.Int64("min"), v.Int64("limit"), v.String("since"), v.String("page"))
+
+	if !argsStruct.Valid() {
+		fmt.Fprintln(writer, argsStruct.Error())
+		return
+	}
+
+	res, hasMore, err := app.listLastDonations(context.Background(), argsStruct)
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting donations: %v\n", err)
+		return
+	}
+
+	if len(res) == 0 {
+		fmt.Fprintf(writer, "No donations found.\n")
+		return
+	}
 
+	table := discord.Table{
+		Header: "Time\tChannel\tDonor\tAmount",
+		More:   hasMore,
+		NextPage: func(next int) string {
+			last := res[next-1]
+			return fmt.Sprintf("Next page: -page %s", encodePageToken(last.Timestamp, last.ID))
+		},
+	}
+	for _, r := range res {
+		table.Rows = append(table.Rows, fmt.Sprintf("%s\t%s\t%s\t%d", r.Timestamp.In(app.cfg.Location).Format("2006-01-02 15:04"), r.Channel, r.SendFrom, r.Amount))
+	}
+	fmt.Fprint(writer, table.Render())
+}
+
+// parseLastDonationsArgs validates the filters of the last donations shared
+// by the Discord command and the HTTP API into argsStruct.
+func (app *application) parseLastDonationsArgs(argsStruct *DiscordGetLastDonationsArgs, channel, donor string, minAmount, limit int64, since, page string) {
+	if channel != "" {
+		argsStruct.Channel = normalizeChannel(channel)
+	}
+	argsStruct.Donor = donor
+	argsStruct.MinAmount = minAmount
+	argsStruct.Limit = limit
+	argsStruct.CheckField(limit > 0 && limit <= 50, "limit", "Limit must be between 1 and 50.")
+	argsStruct.CheckField(minAmount >= 0, "min", "Min must not be negative.")
+	app.handleDateRange(&argsStruct.Validator, argsStruct.Channel, since, "", &argsStruct.Since, &argsStruct.Until)
+	if page != "" {
+		var ok bool
+		argsStruct.CursorTS, argsStruct.CursorID, ok = decodePageToken(page)
+		argsStruct.CheckField(ok, "page", "Invalid page token.")
+	}
+}
+
+// listLastDonations returns one page of donations and whether there is a
+// next one.
+func (app *application) listLastDonations(ctx context.Context, argsStruct *DiscordGetLastDonationsArgs) ([]db.Donation, bool, error) {
+	// one extra row tells whether there is a next page
+	res, err := app.db.ListLastDonations(ctx, db.ListLastDonationsParams{
+		Channel:         argsStruct.Channel,
+		Donor:           argsStruct.Donor,
+		MinAmount:       argsStruct.MinAmount,
+		Since:           argsStruct.Since,
+		Until:           argsStruct.Until,
+		CursorID:        argsStruct.CursorID,
+		CursorTimestamp: argsStruct.CursorTS,
+		RowLimit:        argsStruct.Limit + 1,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := int64(len(res)) > argsStruct.Limit
+	if hasMore {
+		res = res[:argsStruct.Limit]
+	}
+	return res, hasMore, nil
+}
+
+// formatDBDate converts a UTC "YYYY-MM-DD HH:MM:SS" value computed by SQLite
+// into a date in the reporting timezone.
+func (app *application) formatDBDate(value string) string {
+	t, err := time.ParseInLocation(time.DateTime, value, time.UTC)
+	if err != nil {
+		return value
+	}
+	return t.In(app.cfg.Location).Format(time.DateOnly)
+}
+
+// encodePageToken encodes the keyset of the last shown row as short hex.
+func encodePageToken(ts time.Time, id int64) string {
+	return fmt.Sprintf("%x.%x", ts.Unix(), id)
+}
+
+func decodePageToken(token string) (time.Time, int64, bool) {
+	tsPart, idPart, found := strings.Cut(token, ".")
+	if !found {
+		return time.Time{}, 0, false
+	}
+	unix, err := strconv.ParseInt(tsPart, 16, 64)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	id, err := strconv.ParseInt(idPart, 16, 64)
+	if err != nil || id <= 0 {
+		return time.Time{}, 0, false
+	}
+	return time.Unix(unix, 0).UTC(), id, true
 }
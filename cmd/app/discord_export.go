@@ -0,0 +1,100 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/discord"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// exportInlineLimit leaves room for the code fence around an inline export.
+const exportInlineLimit = discord.MessageLimit - 32
+
+var errExportTooLarge = errors.New("export does not fit into a message")
+
+// cappedBuffer fails writes once more than limit bytes were written.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errExportTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// DiscordExport replies with small text exports inline and writes larger
+// ones, and every parquet export, to the configured export directory.
+func (app *application) DiscordExport(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := exportCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+	argsStruct := app.parseExportArgs(v)
+	if !argsStruct.Valid() {
+		fmt.Fprintln(writer, argsStruct.Error())
+		return
+	}
+
+	ctx := context.Background()
+	if !argsStruct.Format.Binary() {
+		buf := &cappedBuffer{limit: exportInlineLimit}
+		written, err := app.exportDonations(ctx, argsStruct, buf)
+		switch {
+		case err == nil && written == 0:
+			fmt.Fprintln(writer, "No donations found.")
+			return
+		case err == nil:
+			fmt.Fprintf(writer, "```%s\n%s```", argsStruct.Format, buf.String())
+			return
+		case !errors.Is(err, errExportTooLarge):
+			fmt.Fprintf(writer, "Error exporting donations: %v\n", err)
+			return
+		}
+	}
+
+	if app.cfg.Export.Dir == "" {
+		fmt.Fprintln(writer, "Export is too large for a message and no export directory is configured.")
+		return
+	}
+	if err := os.MkdirAll(app.cfg.Export.Dir, 0o755); err != nil {
+		fmt.Fprintf(writer, "Error creating export directory: %v\n", err)
+		return
+	}
+	path, err := exportPath(app.cfg.Export.Dir, exportFileName(argsStruct, time.Now().In(app.cfg.Location)))
+	if err != nil {
+		fmt.Fprintf(writer, "Error creating export file: %v\n", err)
+		return
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(writer, "Error creating export file: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	written, err := app.exportDonations(ctx, argsStruct, file)
+	if err != nil {
+		os.Remove(path)
+		fmt.Fprintf(writer, "Error exporting donations: %v\n", err)
+		return
+	}
+	fmt.Fprintf(writer, "Exported %d donations to %s\n", written, path)
+}
+
+// exportPath joins dir and name, failing when the result is not inside dir.
+func exportPath(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel != filepath.Base(rel) || rel == "." || rel == ".." {
+		return "", fmt.Errorf("export file %q is outside of the export directory", name)
+	}
+	return path, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExportPath(t *testing.T) {
+	dir := filepath.Join("exports", "daily")
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"donations-tartancz-20250612-120000.csv", true},
+		{"donations-../../../tmp/x-20250612-120000.csv", false},
+		{"../donations.csv", false},
+		{"sub/donations.csv", false},
+		{"..", false},
+		{".", false},
+	}
+	for _, tt := range tests {
+		path, err := exportPath(dir, tt.name)
+		if (err == nil) != tt.ok {
+			t.Errorf("exportPath(%q) = %q, %v; want ok %v", tt.name, path, err, tt.ok)
+		}
+	}
+}
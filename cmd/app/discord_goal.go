@@ -0,0 +1,223 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/stats"
+	"TwitchDonoCalculator/internal/validator"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	goalCommand = discord.Command{
+		Name:    "goal",
+		Summary: "Track donation goals, milestones at 25/50/75/100% are announced.",
+	}
+	goalListCommand = discord.Command{
+		Name:    "goal list",
+		Summary: "list open goals",
+		Flags: []discord.Flag{
+			{Name: "channel", Usage: "only goals of this channel, all channels when empty"},
+			{Name: "all", Kind: discord.BoolFlag, Usage: "include closed goals"},
+		},
+	}
+	goalCreateCommand = discord.Command{
+		Name:       "goal create",
+		Args:       "<channel> [flags] [title]",
+		Summary:    "start a goal, the title goes last",
+		Permission: discord.LevelModerator,
+		Flags: []discord.Flag{
+			{Name: "target", Kind: discord.Int64Flag, Usage: "amount to raise"},
+			{Name: "currency", Usage: "currency shown next to the amounts"},
+			{Name: "start", Usage: "first day counted YYYY-MM-DD, now when empty"},
+			{Name: "end", Usage: "last day counted YYYY-MM-DD, inclusive, open when empty"},
+		},
+	}
+	goalCloseCommand = discord.Command{
+		Name:       "goal close",
+		Args:       "<id>",
+		Summary:    "close a goal before its end",
+		Permission: discord.LevelModerator,
+	}
+)
+
+func (app *application) discordGoalList(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := goalListCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+
+	params := db.ListGoalProgressParams{
+		IncludeClosed: v.Bool("all"),
+		RowLimit:      25,
+	}
+	if channel := v.String("channel"); channel != "" {
+		params.Channel = normalizeChannel(channel)
+	}
+	res, err := app.db.ListGoalProgress(context.Background(), params)
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting goals: %v\n", err)
+		return
+	}
+	if len(res) == 0 {
+		fmt.Fprintln(writer, "No goals found.")
+		return
+	}
+	fmt.Fprintf(writer, "```%s```", renderGoals(res, app.cfg.Location, time.Now()))
+}
+
+type DiscordGoalCreateArgs struct {
+	Channel  string
+	Title    string
+	Target   int64
+	Currency string
+	Start    time.Time
+	End      sql.NullTime
+	validator.Validator
+}
+
+func (app *application) discordGoalCreate(args discord.DiscordMessageArgs, writer io.Writer) {
+	rest := args.Args
+	if len(rest) == 0 || strings.HasPrefix(rest[0], "-") {
+		fmt.Fprintln(writer, "Missing channel, usage: goal create <channel> -target N [title]")
+		return
+	}
+	channel, rest := normalizeChannel(rest[0]), rest[1:]
+
+	v, err := goalCreateCommand.Parse(rest, writer)
+	if err != nil {
+		return
+	}
+	start, end := v.String("start"), v.String("end")
+
+	var argsStruct DiscordGoalCreateArgs
+	argsStruct.Channel = channel
+	argsStruct.Title = strings.Join(v.Args(), " ")
+	argsStruct.Target = v.Int64("target")
+	argsStruct.Currency = strings.ToUpper(v.String("currency"))
+	argsStruct.CheckField(argsStruct.Target > 0, "target", "Target must be positive.")
+	argsStruct.CheckField(len(argsStruct.Currency) <= 8, "currency", "Currency must be at most 8 characters.")
+	argsStruct.CheckField(len(argsStruct.Title) <= 100, "title", "Title must be at most 100 characters.")
+
+	argsStruct.Start = time.Now()
+	if start != "" {
+		argsStruct.CheckField(validator.ValidAndConvertDateTimeIn(start, time.DateOnly, app.cfg.Location, &argsStruct.Start), "start", "Invalid start date. Use 'YYYY-MM-DD' format.")
+	}
+	if end != "" {
+		ok := validator.ValidAndConvertDateTimeIn(end, time.DateOnly, app.cfg.Location, &argsStruct.End.Time)
+		argsStruct.CheckField(ok, "end", "Invalid end date. Use 'YYYY-MM-DD' format.")
+		if ok {
+			argsStruct.End = sql.NullTime{Time: argsStruct.End.Time.AddDate(0, 0, 1), Valid: true}
+			argsStruct.CheckField(argsStruct.Start.Before(argsStruct.End.Time), "end", "End date must not be before start.")
+		}
+	}
+
+	if !argsStruct.Valid() {
+		fmt.Fprintln(writer, argsStruct.Error())
+		return
+	}
+
+	ctx := context.Background()
+	goal, err := app.db.CreateGoal(ctx, db.CreateGoalParams{
+		Channel:   argsStruct.Channel,
+		Title:     argsStruct.Title,
+		Target:    argsStruct.Target,
+		Currency:  argsStruct.Currency,
+		StartsAt:  argsStruct.Start.UTC(),
+		EndsAt:    sql.NullTime{Time: argsStruct.End.Time.UTC(), Valid: argsStruct.End.Valid},
+		CreatedBy: discordChangedBy,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error creating goal: %v\n", err)
+		return
+	}
+
+	row, err := app.db.GetGoalProgress(ctx, goal.ID)
+	if err != nil {
+		fmt.Fprintf(writer, "Created goal %d, error getting its progress: %v\n", goal.ID, err)
+		return
+	}
+	progress := db.ListGoalProgressRow(row)
+	// a goal starting in the past does not announce milestones it already had
+	if milestone := reachedMilestone(progress.Raised, progress.Target); milestone > 0 {
+		if _, err := app.db.SetGoalMilestone(ctx, db.SetGoalMilestoneParams{Milestone: milestone, ID: goal.ID}); err != nil {
+			app.logger.Error("failed to update goal milestone", "goal", goal.ID, "error", err)
+		}
+	}
+	fmt.Fprintf(writer, "Created goal %s for %s: %s\n", goalName(progress), progress.Channel, goalProgress(progress))
+}
+
+func (app *application) discordGoalClose(args discord.DiscordMessageArgs, writer io.Writer) {
+	rest := args.Args
+	if len(rest) != 1 {
+		fmt.Fprintln(writer, "Usage: goal close <id>")
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(rest[0], "#"), 10, 64)
+	if err != nil || id <= 0 {
+		fmt.Fprintf(writer, "Invalid goal id: %s\n", rest[0])
+		return
+	}
+
+	ctx := context.Background()
+	closed, err := app.db.CloseGoal(ctx, db.CloseGoalParams{
+		ClosedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		ID:       id,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error closing goal: %v\n", err)
+		return
+	}
+
+	row, err := app.db.GetGoalProgress(ctx, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		fmt.Fprintf(writer, "Goal %d does not exist.\n", id)
+	case err != nil:
+		fmt.Fprintf(writer, "Error getting goal: %v\n", err)
+	case closed == 0:
+		fmt.Fprintf(writer, "Goal %d is already closed.\n", id)
+	default:
+		progress := db.ListGoalProgressRow(row)
+		fmt.Fprintf(writer, "Closed goal %s for %s: %s\n", goalName(progress), progress.Channel, goalProgress(progress))
+	}
+}
+
+func renderGoals(rows []db.ListGoalProgressRow, loc *time.Location, now time.Time) string {
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "ID\tChannel\tTitle\tProgress\t\tEnds\tStatus")
+	for _, r := range rows {
+		ends := "-"
+		if r.EndsAt.Valid {
+			// ends_at is the exclusive end, show the last counted day
+			ends = r.EndsAt.Time.In(loc).AddDate(0, 0, -1).Format(time.DateOnly)
+		}
+		fmt.Fprintf(tb, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Channel, r.Title, goalProgress(r), stats.Bar(min(r.Raised, r.Target), r.Target, 10), ends, goalStatus(r, now))
+	}
+	tb.Flush()
+	return buf.String()
+}
+
+func goalStatus(g db.ListGoalProgressRow, now time.Time) string {
+	switch {
+	case g.ClosedAt.Valid:
+		return "closed"
+	case g.Raised >= g.Target:
+		return "reached"
+	case g.EndsAt.Valid && !now.Before(g.EndsAt.Time):
+		return "ended"
+	case now.Before(g.StartsAt):
+		return "upcoming"
+	}
+	return "open"
+}
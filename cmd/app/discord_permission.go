@@ -0,0 +1,252 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Kinds of discord_permission rows.
+const (
+	permissionUser = "user"
+	permissionRole = "role"
+)
+
+// discordGuard gives Discord callers the highest level granted to them or
+// one of their roles, discord.admins are always admins. Every command is
+// written to the audit log.
+type discordGuard struct {
+	app *application
+}
+
+func (g discordGuard) Level(ctx context.Context, caller discord.Caller) (discord.Level, error) {
+	cfg := g.app.cfg.Discord
+	if caller.Anonymous() {
+		return discord.ParseLevel(cfg.AnonymousLevel)
+	}
+	for _, id := range cfg.Admins {
+		if id == caller.UserID {
+			return discord.LevelAdmin, nil
+		}
+	}
+
+	permissions, err := g.app.db.ListDiscordPermissions(ctx)
+	if err != nil {
+		return discord.LevelEveryone, err
+	}
+	level := discord.LevelEveryone
+	for _, p := range permissions {
+		if !grants(p, caller) {
+			continue
+		}
+		granted, err := discord.ParseLevel(p.Level)
+		if err != nil {
+			g.app.logger.Warn("ignoring invalid Discord permission", "kind", p.Kind, "id", p.SubjectID, "level", p.Level)
+			continue
+		}
+		level = max(level, granted)
+	}
+	return level, nil
+}
+
+func grants(p db.DiscordPermission, caller discord.Caller) bool {
+	switch p.Kind {
+	case permissionUser:
+		return p.SubjectID == caller.UserID
+	case permissionRole:
+		for _, role := range caller.RoleIDs {
+			if role == p.SubjectID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g discordGuard) Audit(ctx context.Context, args discord.DiscordMessageArgs, required discord.Level, allowed bool) {
+	err := g.app.db.CreateDiscordAuditEntry(ctx, db.CreateDiscordAuditEntryParams{
+		UserID:        args.Caller.UserID,
+		ChannelID:     args.Caller.ChannelID,
+		RoleIds:       strings.Join(args.Caller.RoleIDs, ","),
+		Command:       args.CommandName,
+		Line:          args.Raw,
+		Level:         args.Level.String(),
+		RequiredLevel: required.String(),
+		Allowed:       allowed,
+	})
+	if err != nil {
+		g.app.logger.Error("failed to write Discord audit log", "command", args.CommandName, "user", args.Caller.UserID, "error", err)
+	}
+}
+
+var permissionAuditCommand = discord.Command{
+	Name:    "permission audit",
+	Summary: "show the most recent commands",
+	Flags: []discord.Flag{
+		{Name: "user", Usage: "only commands of this Discord user id"},
+		{Name: "limit", Kind: discord.Int64Flag, Default: "15", Usage: "number of commands (max 50)"},
+	},
+}
+
+var (
+	permissionCommand = discord.Command{
+		Name:       "permission",
+		Summary:    "Grant Discord users and roles the moderator or admin level.",
+		Permission: discord.LevelAdmin,
+	}
+	permissionListCommand   = discord.Command{Name: "permission list"}
+	permissionGrantCommand  = discord.Command{Name: "permission grant", Args: "user|role <id> everyone|moderator|admin"}
+	permissionRevokeCommand = discord.Command{Name: "permission revoke", Args: "user|role <id>"}
+)
+
+func (app *application) discordPermissionList(args discord.DiscordMessageArgs, writer io.Writer) {
+	rows, err := app.db.ListDiscordPermissions(context.Background())
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting permissions: %v\n", err)
+		return
+	}
+	if len(rows) == 0 && len(app.cfg.Discord.Admins) == 0 {
+		fmt.Fprintln(writer, "No permissions granted.")
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "Kind\tID\tLevel\tGranted by\tUpdated")
+	for _, id := range app.cfg.Discord.Admins {
+		fmt.Fprintf(tb, "%s\t%s\t%s\t%s\t%s\n", permissionUser, id, discord.LevelAdmin, "config", "-")
+	}
+	for _, r := range rows {
+		fmt.Fprintf(tb, "%s\t%s\t%s\t%s\t%s\n", r.Kind, r.SubjectID, r.Level, r.GrantedBy, r.UpdatedAt.In(app.cfg.Location).Format("2006-01-02 15:04"))
+	}
+	tb.Flush()
+	fmt.Fprintf(writer, "```%s```", buf.String())
+}
+
+func (app *application) discordPermissionGrant(args discord.DiscordMessageArgs, writer io.Writer) {
+	rest := args.Args
+	if len(rest) != 3 {
+		fmt.Fprintln(writer, "Usage: permission grant user|role <id> everyone|moderator|admin")
+		return
+	}
+	kind, id, ok := parsePermissionSubject(writer, rest)
+	if !ok {
+		return
+	}
+	level, err := discord.ParseLevel(strings.ToLower(rest[2]))
+	if err != nil {
+		fmt.Fprintf(writer, "Invalid level: %v\n", err)
+		return
+	}
+
+	grantedBy := args.Caller.UserID
+	if args.Caller.Anonymous() {
+		grantedBy = "anonymous"
+	}
+	p, err := app.db.UpsertDiscordPermission(context.Background(), db.UpsertDiscordPermissionParams{
+		Kind:      kind,
+		SubjectID: id,
+		Level:     level.String(),
+		GrantedBy: grantedBy,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error granting permission: %v\n", err)
+		return
+	}
+	fmt.Fprintf(writer, "Granted %s %s the %s level.\n", p.Kind, p.SubjectID, p.Level)
+}
+
+func (app *application) discordPermissionRevoke(args discord.DiscordMessageArgs, writer io.Writer) {
+	rest := args.Args
+	if len(rest) != 2 {
+		fmt.Fprintln(writer, "Usage: permission revoke user|role <id>")
+		return
+	}
+	kind, id, ok := parsePermissionSubject(writer, rest)
+	if !ok {
+		return
+	}
+	deleted, err := app.db.DeleteDiscordPermission(context.Background(), db.DeleteDiscordPermissionParams{
+		Kind:      kind,
+		SubjectID: id,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error revoking permission: %v\n", err)
+		return
+	}
+	if deleted == 0 {
+		fmt.Fprintf(writer, "%s %s has no permission granted.\n", kind, id)
+		return
+	}
+	fmt.Fprintf(writer, "Revoked the permission of %s %s.\n", kind, id)
+}
+
+// parsePermissionSubject reads "user|role <id>", mentions like <@123> and
+// <@&123> are accepted as ids.
+func parsePermissionSubject(writer io.Writer, rest []string) (string, string, bool) {
+	kind := strings.ToLower(rest[0])
+	if kind != permissionUser && kind != permissionRole {
+		fmt.Fprintf(writer, "Invalid kind %q, use user or role.\n", rest[0])
+		return "", "", false
+	}
+	id := strings.TrimSuffix(strings.TrimLeft(rest[1], "<@!&"), ">")
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		fmt.Fprintf(writer, "Invalid Discord id: %s\n", rest[1])
+		return "", "", false
+	}
+	return kind, id, true
+}
+
+func (app *application) discordPermissionAudit(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := permissionAuditCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+	limit := v.Int64("limit")
+	if limit <= 0 || limit > 50 {
+		fmt.Fprintln(writer, "Limit must be between 1 and 50.")
+		return
+	}
+
+	rows, err := app.db.ListDiscordAuditLog(context.Background(), db.ListDiscordAuditLogParams{
+		UserID:   v.String("user"),
+		RowLimit: limit,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting audit log: %v\n", err)
+		return
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(writer, "No commands recorded.")
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "Time\tUser\tLevel\tAllowed\tCommand")
+	for _, r := range rows {
+		user := r.UserID
+		if user == "" {
+			user = "anonymous"
+		}
+		fmt.Fprintf(tb, "%s\t%s\t%s\t%t\t%s\n", r.CreatedAt.In(app.cfg.Location).Format(time.DateTime), user, r.Level, r.Allowed, shortenLine(r.Line, 60))
+	}
+	tb.Flush()
+	fmt.Fprintf(writer, "```%s```", buf.String())
+}
+
+// shortenLine cuts a command line for a table cell.
+func shortenLine(line string, limit int) string {
+	line = strings.Join(strings.Fields(line), " ")
+	if len(line) <= limit {
+		return line
+	}
+	return strings.ToValidUTF8(line[:limit-3], "") + "..."
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/validator"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+type DiscordGetSessionsArgs struct {
+	Channel string
+	Limit   int64
+	validator.Validator
+}
+
+var sessionCommand = discord.Command{
+	Name:    "session",
+	Summary: "Get the current and past stream sessions with their donations.",
+	Flags: []discord.Flag{
+		{Name: "channel", Usage: "only sessions of this channel, all channels when empty"},
+		{Name: "limit", Kind: discord.Int64Flag, Default: "5", Usage: "number of sessions (max 25)"},
+	},
+}
+
+func (app *application) DiscordGetSessions(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := sessionCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+
+	var argsStruct DiscordGetSessionsArgs
+	if channel := v.String("channel"); channel != "" {
+		argsStruct.Channel = normalizeChannel(channel)
+	}
+	argsStruct.Limit = v.Int64("limit")
+	argsStruct.CheckField(argsStruct.Limit > 0 && argsStruct.Limit <= 25, "limit", "Limit must be between 1 and 25.")
+
+	if !argsStruct.Valid() {
+		fmt.Fprintln(writer, argsStruct.Error())
+		return
+	}
+
+	res, err := app.db.ListStreamSessions(context.Background(), db.ListStreamSessionsParams{
+		Channel:  argsStruct.Channel,
+		RowLimit: argsStruct.Limit,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting sessions: %v\n", err)
+		return
+	}
+
+	if len(res) == 0 {
+		fmt.Fprintf(writer, "No stream sessions found.\n")
+		return
+	}
+
+	fmt.Fprintf(writer, "```%s```", renderSessions(res, app.cfg.Location, time.Now()))
+}
+
+func renderSessions(rows []db.ListStreamSessionsRow, loc *time.Location, now time.Time) string {
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "Channel\tStarted\tDuration\tStatus\tDonations\tTotal\tLargest")
+	for _, r := range rows {
+		status, end := "live", now
+		if r.EndedAt.Valid {
+			status, end = "ended", r.EndedAt.Time
+		}
+		fmt.Fprintf(tb, "%s\t%s\t%s\t%s\t%d\t%d\t%d\n", r.Channel, r.StartedAt.In(loc).Format("2006-01-02 15:04"), formatSessionDuration(end.Sub(r.StartedAt)), status, r.Donations, r.Total, r.Largest)
+	}
+	tb.Flush()
+	return buf.String()
+}
+
+// formatSessionDuration prints d as "3h05m".
+func formatSessionDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
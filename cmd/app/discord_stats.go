@@ -0,0 +1,169 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/stats"
+	"TwitchDonoCalculator/internal/validator"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	statsDefaultBuckets = 14
+	statsMaxBuckets     = 100
+	statsBarWidth       = 20
+)
+
+type DiscordGetStatsArgs struct {
+	Channel  string
+	Interval stats.Interval
+	Location *time.Location
+	From     time.Time
+	To       time.Time
+	validator.Validator
+}
+
+var statsCommand = discord.Command{
+	Name:    "stats",
+	Summary: "Get donation count, sum, average, median and max per bucket.",
+	Flags: []discord.Flag{
+		{Name: "channel", Usage: "only this channel, all channels when empty"},
+		{Name: "by", Default: "day", Usage: "bucket size: hour, day, week or month"},
+		{Name: "tz", Usage: "timezone used for buckets, e.g. Europe/Prague, the configured one when empty"},
+		{Name: "from", Usage: fmt.Sprintf("%s (default last %d buckets)", dateFromUsage, statsDefaultBuckets)},
+		{Name: "to", Usage: dateToUsage},
+	},
+}
+
+func (app *application) DiscordGetStats(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := statsCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+
+	tz := v.String("tz")
+	if tz == "" {
+		tz = app.cfg.Timezone
+	}
+	argsStruct := app.parseStatsArgs(v.String("channel"), strings.ToLower(v.String("by")), tz, v.String("from"), v.String("to"))
+	if !argsStruct.Valid() {
+		fmt.Fprintln(writer, argsStruct.Error())
+		return
+	}
+
+	res, err := app.loadStats(context.Background(), argsStruct)
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting donations: %v\n", err)
+		return
+	}
+
+	if len(res) == 0 {
+		fmt.Fprintf(writer, "No donations found.\n")
+		return
+	}
+
+	for _, cs := range res {
+		fmt.Fprint(writer, renderStats(cs.Channel, argsStruct.Interval, argsStruct.Location, cs.Buckets))
+	}
+}
+
+// parseStatsArgs validates the stats filters shared by the Discord command
+// and the HTTP API. An empty from selects the last statsDefaultBuckets
+// buckets.
+func (app *application) parseStatsArgs(channel, by, tz, from, to string) *DiscordGetStatsArgs {
+	argsStruct := &DiscordGetStatsArgs{}
+	if channel != "" {
+		argsStruct.Channel = normalizeChannel(channel)
+	}
+	interval, err := stats.ParseInterval(by)
+	argsStruct.CheckField(err == nil, "by", "By must be one of hour, day, week or month.")
+	argsStruct.Interval = interval
+	argsStruct.Location, err = time.LoadLocation(tz)
+	argsStruct.CheckField(err == nil, "tz", "Unknown timezone, use a name like Europe/Prague.")
+	if strings.EqualFold(from, lastStreamName) {
+		app.handleDateRange(&argsStruct.Validator, argsStruct.Channel, from, to, &argsStruct.From, &argsStruct.To)
+	} else if argsStruct.Location != nil {
+		validator.HandleDateRange(&argsStruct.Validator, from, to, argsStruct.Location, &argsStruct.From, &argsStruct.To)
+	}
+	if !argsStruct.Valid() {
+		return argsStruct
+	}
+
+	if from == "" {
+		start := interval.Truncate(argsStruct.To.Add(-time.Nanosecond), argsStruct.Location)
+		for range statsDefaultBuckets - 1 {
+			start = interval.Truncate(start.Add(-time.Nanosecond), argsStruct.Location)
+		}
+		argsStruct.From = start
+	}
+	if n := stats.CountBuckets(interval, argsStruct.Location, argsStruct.From, argsStruct.To, statsMaxBuckets); n > statsMaxBuckets {
+		argsStruct.AddFieldError("from", fmt.Sprintf("Range has more than %d %s buckets. Use a shorter range or a bigger by.", statsMaxBuckets, interval))
+	}
+	return argsStruct
+}
+
+type channelStats struct {
+	Channel string
+	Buckets []stats.Bucket
+}
+
+// loadStats buckets the donations selected by argsStruct per channel, it
+// returns nothing when there are no donations in the range.
+func (app *application) loadStats(ctx context.Context, argsStruct *DiscordGetStatsArgs) ([]channelStats, error) {
+	res, err := app.db.ListDonationAmounts(ctx, db.ListDonationAmountsParams{
+		Channel:       argsStruct.Channel,
+		FromTimestamp: argsStruct.From,
+		ToTimestamp:   argsStruct.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// rows are ordered by channel, so each channel is one contiguous run
+	var channels []string
+	points := make(map[string][]stats.Point)
+	for _, r := range res {
+		if _, exists := points[r.Channel]; !exists {
+			channels = append(channels, r.Channel)
+		}
+		points[r.Channel] = append(points[r.Channel], stats.Point{Time: r.Timestamp, Amount: r.Amount})
+	}
+
+	result := make([]channelStats, 0, len(channels))
+	for _, ch := range channels {
+		result = append(result, channelStats{
+			Channel: ch,
+			Buckets: stats.BucketPoints(points[ch], argsStruct.Interval, argsStruct.Location, argsStruct.From, argsStruct.To),
+		})
+	}
+	return result, nil
+}
+
+func renderStats(channel string, interval stats.Interval, loc *time.Location, buckets []stats.Bucket) string {
+	sums := make([]int64, len(buckets))
+	var total, count, maxSum int64
+	for i, b := range buckets {
+		sums[i] = b.Sum
+		total += b.Sum
+		count += b.Count
+		maxSum = max(maxSum, b.Sum)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s per %s (%s), %d donations, total %d\n", channel, interval, loc, count, total)
+	fmt.Fprintf(buf, "[%s]\n", stats.Sparkline(sums))
+
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "Bucket\tCount\tSum\tAvg\tMedian\tMax\t")
+	for _, b := range buckets {
+		fmt.Fprintf(tb, "%s\t%d\t%d\t%.0f\t%.0f\t%d\t%s\n", interval.Format(b.Start), b.Count, b.Sum, b.Avg, b.Median, b.Max, stats.Bar(b.Sum, maxSum, statsBarWidth))
+	}
+	tb.Flush()
+	return fmt.Sprintf("```%s```\n", buf.String())
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/validator"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const discordChangedBy = "discord"
+
+// streamerFlags are the settings of a streamer, edit changes only the ones
+// passed.
+var streamerFlags = []discord.Flag{
+	{Name: "bot", Usage: "name of the bot announcing donations"},
+	{Name: "regex", Usage: "regex matching the donated amount, quote it when it has spaces"},
+	{Name: "filter", Usage: "text a donation message must contain"},
+	{Name: "notify", Kind: discord.Int64Flag, Usage: "amount from which donations are announced"},
+	{Name: "log", Kind: discord.BoolFlag, Usage: "log chat messages of the channel"},
+}
+
+var (
+	streamerCommand = discord.Command{
+		Name:       "streamer",
+		Summary:    "Manage tracked streamers, changes apply to the live Twitch connection.",
+		Permission: discord.LevelModerator,
+	}
+	streamerListCommand    = discord.Command{Name: "streamer list"}
+	streamerAddCommand     = discord.Command{Name: "streamer add", Args: "<channel> [flags]", Summary: "track a channel, -bot and -regex are required", Flags: streamerFlags, Permission: discord.LevelAdmin}
+	streamerEditCommand    = discord.Command{Name: "streamer edit", Args: "<channel> [flags]", Summary: "change the settings passed", Flags: streamerFlags, Permission: discord.LevelAdmin}
+	streamerDisableCommand = discord.Command{Name: "streamer disable", Args: "<channel>", Permission: discord.LevelAdmin}
+	streamerEnableCommand  = discord.Command{Name: "streamer enable", Args: "<channel>", Permission: discord.LevelAdmin}
+	streamerTestCommand    = discord.Command{Name: "streamer test", Args: "<channel> <sample text>", Summary: "run a chat message through the settings"}
+	streamerHistoryCommand = discord.Command{Name: "streamer history", Args: "<channel>"}
+)
+
+// streamerChannel returns the channel a streamer command starts with and the
+// arguments after it.
+func streamerChannel(args discord.DiscordMessageArgs, writer io.Writer) (string, []string, bool) {
+	if len(args.Args) == 0 || strings.HasPrefix(args.Args[0], "-") {
+		fmt.Fprintf(writer, "Missing channel, usage: %s <channel>\n", args.CommandName)
+		return "", nil, false
+	}
+	return normalizeChannel(args.Args[0]), args.Args[1:], true
+}
+
+func (app *application) discordStreamerAdd(args discord.DiscordMessageArgs, writer io.Writer) {
+	if channel, rest, ok := streamerChannel(args, writer); ok {
+		app.discordStreamerSave(writer, true, channel, rest)
+	}
+}
+
+func (app *application) discordStreamerEdit(args discord.DiscordMessageArgs, writer io.Writer) {
+	if channel, rest, ok := streamerChannel(args, writer); ok {
+		app.discordStreamerSave(writer, false, channel, rest)
+	}
+}
+
+func (app *application) discordStreamerEnable(args discord.DiscordMessageArgs, writer io.Writer) {
+	if channel, _, ok := streamerChannel(args, writer); ok {
+		app.discordStreamerSetEnabled(writer, channel, true)
+	}
+}
+
+func (app *application) discordStreamerDisable(args discord.DiscordMessageArgs, writer io.Writer) {
+	if channel, _, ok := streamerChannel(args, writer); ok {
+		app.discordStreamerSetEnabled(writer, channel, false)
+	}
+}
+
+func (app *application) discordStreamerList(args discord.DiscordMessageArgs, writer io.Writer) {
+	rows, err := app.db.ListStreamers(context.Background())
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting streamers: %v\n", err)
+		return
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(writer, "No streamers configured.")
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "Channel\tBot\tRegex\tFilter\tNotify\tEnabled\tUpdated")
+	for _, r := range rows {
+		fmt.Fprintf(tb, "%s\t%s\t%s\t%s\t%d\t%t\t%s\n", r.Channel, r.BotName, r.ValueRegex, r.LineFilterContain, r.NotifyThreshold, r.Enabled, r.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	tb.Flush()
+	fmt.Fprintf(writer, "```%s```", buf.String())
+}
+
+func (app *application) discordStreamerSave(writer io.Writer, create bool, channel string, rest []string) {
+	ctx := context.Background()
+	existing, err := app.db.GetStreamerByChannel(ctx, channel)
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		fmt.Fprintf(writer, "Error getting streamer: %v\n", err)
+		return
+	case err == nil && create:
+		fmt.Fprintf(writer, "%s is already tracked, use streamer edit.\n", channel)
+		return
+	case err != nil && !create:
+		fmt.Fprintf(writer, "%s is not tracked, use streamer add.\n", channel)
+		return
+	}
+
+	sc := config.StreamerConfig{}
+	if !create {
+		sc = *streamerConfigFromDB(existing)
+	}
+
+	command := streamerEditCommand
+	if create {
+		command = streamerAddCommand
+	}
+	flags, err := command.Parse(rest, writer)
+	if err != nil {
+		// the flag set already wrote the error or usage to writer
+		return
+	}
+	if flags.IsSet("bot") {
+		// chat messages carry the lowercase login of the sender
+		sc.BotName = strings.ToLower(flags.String("bot"))
+	}
+	if flags.IsSet("regex") {
+		sc.ValueRegex = flags.String("regex")
+	}
+	if flags.IsSet("filter") {
+		sc.LineFilterContain = flags.String("filter")
+	}
+	if flags.IsSet("notify") {
+		sc.NotifyThreshold = flags.Int64("notify")
+	}
+	if flags.IsSet("log") {
+		sc.LogMessage = flags.Bool("log")
+	}
+
+	v := &validator.Validator{}
+	config.ValidateStreamer(v, channel, &sc)
+	if !v.Valid() {
+		fmt.Fprintln(writer, v.Error())
+		return
+	}
+
+	if _, err := app.SaveStreamer(ctx, channel, sc, discordChangedBy); err != nil {
+		fmt.Fprintf(writer, "Error saving streamer: %v\n", err)
+		return
+	}
+	app.discordReloadStreamers(writer, fmt.Sprintf("Saved %s.", channel))
+}
+
+func (app *application) discordStreamerSetEnabled(writer io.Writer, channel string, enabled bool) {
+	ctx := context.Background()
+	existing, err := app.db.GetStreamerByChannel(ctx, channel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Fprintf(writer, "%s is not tracked.\n", channel)
+			return
+		}
+		fmt.Fprintf(writer, "Error getting streamer: %v\n", err)
+		return
+	}
+	if existing.Enabled == enabled {
+		fmt.Fprintf(writer, "%s is already %s.\n", channel, enabledWord(enabled))
+		return
+	}
+
+	sc := streamerConfigFromDB(existing)
+	sc.Disabled = !enabled
+	if _, err := app.SaveStreamer(ctx, channel, *sc, discordChangedBy); err != nil {
+		fmt.Fprintf(writer, "Error saving streamer: %v\n", err)
+		return
+	}
+	app.discordReloadStreamers(writer, fmt.Sprintf("%s %s.", channel, enabledWord(enabled)))
+}
+
+func (app *application) discordStreamerTest(args discord.DiscordMessageArgs, writer io.Writer) {
+	channel, rest, ok := streamerChannel(args, writer)
+	if !ok {
+		return
+	}
+	text := strings.Join(rest, " ")
+	if text == "" {
+		fmt.Fprintln(writer, "Missing sample text, usage: streamer test <channel> <sample text>")
+		return
+	}
+	existing, err := app.db.GetStreamerByChannel(context.Background(), channel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Fprintf(writer, "%s is not tracked.\n", channel)
+			return
+		}
+		fmt.Fprintf(writer, "Error getting streamer: %v\n", err)
+		return
+	}
+	streamer, err := NewStreamer(*streamerConfigFromDB(existing), channel)
+	if err != nil {
+		fmt.Fprintf(writer, "Invalid streamer config: %v\n", err)
+		return
+	}
+
+	switch value := streamer.FindDonation(text); {
+	case value != 0:
+		fmt.Fprintf(writer, "Found donation: %d\n", value)
+	case !strings.Contains(text, streamer.LineFilterContain):
+		fmt.Fprintf(writer, "No donation found, text does not contain %q.\n", streamer.LineFilterContain)
+	default:
+		fmt.Fprintf(writer, "No donation found, regex %s does not match a number.\n", streamer.RegFind)
+	}
+}
+
+func (app *application) discordStreamerHistory(args discord.DiscordMessageArgs, writer io.Writer) {
+	channel, _, ok := streamerChannel(args, writer)
+	if !ok {
+		return
+	}
+	rows, err := app.db.ListStreamerHistory(context.Background(), db.ListStreamerHistoryParams{
+		Channel: channel,
+		Limit:   10,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting history: %v\n", err)
+		return
+	}
+	if len(rows) == 0 {
+		fmt.Fprintf(writer, "No history for %s.\n", channel)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "Changed\tBy\tBot\tRegex\tFilter\tNotify\tEnabled")
+	for _, r := range rows {
+		fmt.Fprintf(tb, "%s\t%s\t%s\t%s\t%s\t%d\t%t\n", r.ChangedAt.Format("2006-01-02 15:04"), r.ChangedBy, r.BotName, r.ValueRegex, r.LineFilterContain, r.NotifyThreshold, r.Enabled)
+	}
+	tb.Flush()
+	fmt.Fprintf(writer, "```%s```", buf.String())
+}
+
+func (app *application) discordReloadStreamers(writer io.Writer, saved string) {
+	diff, err := app.ReloadStreamers(context.Background())
+	if err != nil {
+		fmt.Fprintf(writer, "%s Reloading streamers failed: %v\n", saved, err)
+		return
+	}
+	fmt.Fprintf(writer, "%s Streamers reloaded: %s\n", saved, diff)
+}
+
+func enabledWord(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/twitch"
+	"strings"
+	"testing"
+)
+
+var testAdmin = discord.Caller{UserID: "80351110224678912"}
+
+// addTestStreamer tracks #tartancz through the streamer command.
+func addTestStreamer(t *testing.T, app *application, recorder *discord.Recorder) {
+	t.Helper()
+	app.cfg.Discord.Admins = []string{testAdmin.UserID}
+	out := recorder.RunAs(testAdmin, `streamer add tartancz -bot DonoBot -filter sent -regex "\d+" -notify 500`)
+	if !strings.Contains(out, "Saved #tartancz.") {
+		t.Fatalf("streamer add: %s", out)
+	}
+	if app.GetStreamer("#tartancz") == nil {
+		t.Fatal("#tartancz is not tracked after streamer add")
+	}
+}
+
+func TestDiscordHelp(t *testing.T) {
+	_, recorder := newTestApp(t)
+	out := recorder.Run("help")
+	for _, want := range []string{"donation top", "goal create", "streamer add", "webhook redeliver"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("help does not list %s:\n%s", want, out)
+		}
+	}
+	if out := recorder.Run("tpo"); !strings.Contains(out, "Did you mean top?") {
+		t.Errorf("no suggestion for tpo:\n%s", out)
+	}
+	if out := recorder.Run("help goal"); !strings.Contains(out, "goal create") || strings.Contains(out, "streamer") {
+		t.Errorf("help goal:\n%s", out)
+	}
+}
+
+func TestDiscordPermissions(t *testing.T) {
+	app, recorder := newTestApp(t)
+	out := recorder.Run("streamer add tartancz -bot b -regex x")
+	if !strings.Contains(out, "Permission denied") {
+		t.Errorf("anonymous streamer add was not denied:\n%s", out)
+	}
+	out = recorder.RunAs(discord.Caller{UserID: "1"}, "permission grant user 2 admin")
+	if !strings.Contains(out, "Permission denied") {
+		t.Errorf("permission grant by a user was not denied:\n%s", out)
+	}
+	addTestStreamer(t, app, recorder)
+
+	for _, channel := range []string{"\"x\nPART #y\"", "../../tmp/x", "a/b"} {
+		out := recorder.RunAs(testAdmin, "streamer add "+channel+" -bot b -regex x")
+		if !strings.Contains(out, "Twitch login") {
+			t.Errorf("streamer add %s was not rejected:\n%s", channel, out)
+		}
+	}
+	if channels := app.StreamerChannels(); len(channels) != 1 {
+		t.Errorf("tracked %v, want only #tartancz", channels)
+	}
+
+	audit := recorder.RunAs(testAdmin, "permission audit")
+	for _, want := range []string{"streamer add", "permission grant"} {
+		if !strings.Contains(audit, want) {
+			t.Errorf("audit log has no %s:\n%s", want, audit)
+		}
+	}
+}
+
+func TestDiscordDonations(t *testing.T) {
+	app, recorder := newTestApp(t)
+	addTestStreamer(t, app, recorder)
+	recorder.Reset()
+
+	for _, text := range []string{"alice sent 100 CZK", "bob sent 700 CZK", "alice sent 300 CZK"} {
+		app.HandleChatMessage(&twitch.MessagePrivate{Sender: "donobot", Streamer: "#tartancz", Text: text})
+	}
+
+	alerts := recorder.Recorded()
+	if len(alerts) != 1 || alerts[0].Channel != "#tartancz" || !strings.Contains(alerts[0].Text, "700") {
+		t.Errorf("alerts = %+v, want one about 700 in #tartancz", alerts)
+	}
+	out := recorder.Run("top -channel tartancz")
+	if a, b := strings.Index(out, "bob"), strings.Index(out, "alice"); a < 0 || b < 0 || a > b {
+		t.Errorf("top is not ordered by amount:\n%s", out)
+	}
+	if out := recorder.Run("last -channel tartancz"); !strings.Contains(out, "alice") {
+		t.Errorf("last:\n%s", out)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/validator"
+	"TwitchDonoCalculator/internal/webhook"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var webhookDeliveriesCommand = discord.Command{
+	Name: "webhook deliveries",
+	Flags: []discord.Flag{
+		{Name: "webhook", Usage: "only deliveries of this webhook, all when empty"},
+		{Name: "status", Usage: "only deliveries with this status: pending, delivered or dead"},
+		{Name: "limit", Kind: discord.Int64Flag, Default: "10", Usage: "number of deliveries (max 25)"},
+	},
+}
+
+var (
+	webhookCommand = discord.Command{
+		Name:       "webhook",
+		Summary:    "Inspect webhook deliveries, webhooks are configured in the config file.",
+		Permission: discord.LevelModerator,
+	}
+	webhookListCommand      = discord.Command{Name: "webhook list"}
+	webhookShowCommand      = discord.Command{Name: "webhook show", Args: "<id>"}
+	webhookRedeliverCommand = discord.Command{Name: "webhook redeliver", Args: "<id>", Permission: discord.LevelAdmin}
+)
+
+// maxWebhookError bounds the error shown per delivery in tables.
+const maxWebhookError = 40
+
+func (app *application) discordWebhookList(args discord.DiscordMessageArgs, writer io.Writer) {
+	targets := app.webhooks.Targets()
+	if len(targets) == 0 {
+		fmt.Fprintln(writer, "No webhooks configured.")
+		return
+	}
+	counts, err := app.db.CountWebhookDeliveries(context.Background())
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting deliveries: %v\n", err)
+		return
+	}
+	byStatus := make(map[[2]string]int64, len(counts))
+	for _, c := range counts {
+		byStatus[[2]string{c.Webhook, c.Status}] = c.Deliveries
+	}
+
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "Name\tURL\tChannels\tDelivered\tPending\tDead")
+	for _, t := range targets {
+		channels := "all"
+		if len(t.Channels) > 0 {
+			channels = strings.Join(t.Channels, ",")
+		}
+		fmt.Fprintf(tb, "%s\t%s\t%s\t%d\t%d\t%d\n", t.Name, t.URL, channels,
+			byStatus[[2]string{t.Name, webhook.StatusDelivered}],
+			byStatus[[2]string{t.Name, webhook.StatusPending}],
+			byStatus[[2]string{t.Name, webhook.StatusDead}])
+	}
+	tb.Flush()
+	fmt.Fprintf(writer, "```%s```", buf.String())
+}
+
+type DiscordWebhookDeliveriesArgs struct {
+	Webhook string
+	Status  string
+	Limit   int64
+	validator.Validator
+}
+
+func (app *application) discordWebhookDeliveries(args discord.DiscordMessageArgs, writer io.Writer) {
+	v, err := webhookDeliveriesCommand.Parse(args.Args, writer)
+	if err != nil {
+		return
+	}
+
+	var argsStruct DiscordWebhookDeliveriesArgs
+	// webhook names are lowercase, see config.Webhooks
+	argsStruct.Webhook = strings.ToLower(v.String("webhook"))
+	argsStruct.Status = strings.ToLower(v.String("status"))
+	argsStruct.Limit = v.Int64("limit")
+	argsStruct.CheckField(slices.Contains([]string{"", webhook.StatusPending, webhook.StatusDelivered, webhook.StatusDead}, argsStruct.Status), "status", "Status must be pending, delivered or dead.")
+	argsStruct.CheckField(argsStruct.Limit > 0 && argsStruct.Limit <= 25, "limit", "Limit must be between 1 and 25.")
+	if !argsStruct.Valid() {
+		fmt.Fprintln(writer, argsStruct.Error())
+		return
+	}
+
+	res, err := app.db.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{
+		Webhook:  argsStruct.Webhook,
+		Status:   argsStruct.Status,
+		RowLimit: argsStruct.Limit,
+	})
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting deliveries: %v\n", err)
+		return
+	}
+	if len(res) == 0 {
+		fmt.Fprintln(writer, "No webhook deliveries found.")
+		return
+	}
+	fmt.Fprintf(writer, "```%s```", renderWebhookDeliveries(res, app.cfg.Location))
+}
+
+func renderWebhookDeliveries(rows []db.WebhookDelivery, loc *time.Location) string {
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, "ID\tWebhook\tChannel\tEvent\tStatus\tAttempts\tCreated\tLast error")
+	for _, d := range rows {
+		lastError := d.LastError
+		if len(lastError) > maxWebhookError {
+			lastError = lastError[:maxWebhookError-3] + "..."
+		}
+		fmt.Fprintf(tb, "%d\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", d.ID, d.Webhook, d.Channel, d.Event, d.Status, d.Attempts,
+			d.CreatedAt.In(loc).Format(time.DateTime), lastError)
+	}
+	tb.Flush()
+	return buf.String()
+}
+
+func (app *application) discordWebhookShow(args discord.DiscordMessageArgs, writer io.Writer) {
+	id, ok := parseWebhookDeliveryID(args, writer)
+	if !ok {
+		return
+	}
+	d, err := app.db.GetWebhookDelivery(context.Background(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Fprintf(writer, "Webhook delivery %d does not exist.\n", id)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(writer, "Error getting delivery: %v\n", err)
+		return
+	}
+
+	loc := app.cfg.Location
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tb, "Delivery\t%d\n", d.ID)
+	fmt.Fprintf(tb, "Webhook\t%s\n", d.Webhook)
+	fmt.Fprintf(tb, "Event\t%s (%s)\n", d.Event, d.Channel)
+	fmt.Fprintf(tb, "Status\t%s after %d attempts\n", d.Status, d.Attempts)
+	fmt.Fprintf(tb, "Created\t%s\n", d.CreatedAt.In(loc).Format(time.DateTime))
+	switch d.Status {
+	case webhook.StatusDelivered:
+		if d.DeliveredAt.Valid {
+			fmt.Fprintf(tb, "Delivered\t%s\n", d.DeliveredAt.Time.In(loc).Format(time.DateTime))
+		}
+	case webhook.StatusPending:
+		fmt.Fprintf(tb, "Next attempt\t%s\n", d.NextAttemptAt.In(loc).Format(time.DateTime))
+	}
+	if d.LastStatusCode != 0 {
+		fmt.Fprintf(tb, "Last status\t%d\n", d.LastStatusCode)
+	}
+	if d.LastError != "" {
+		fmt.Fprintf(tb, "Last error\t%s\n", d.LastError)
+	}
+	tb.Flush()
+	fmt.Fprintf(writer, "```%s\n%s```", buf.String(), d.Payload)
+}
+
+func (app *application) discordWebhookRedeliver(args discord.DiscordMessageArgs, writer io.Writer) {
+	id, ok := parseWebhookDeliveryID(args, writer)
+	if !ok {
+		return
+	}
+	ctx := context.Background()
+	queued, err := app.webhooks.Redeliver(ctx, id)
+	if err != nil {
+		fmt.Fprintf(writer, "Error redelivering: %v\n", err)
+		return
+	}
+	if queued {
+		fmt.Fprintf(writer, "Queued webhook delivery %d again.\n", id)
+		return
+	}
+
+	_, err = app.db.GetWebhookDelivery(ctx, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		fmt.Fprintf(writer, "Webhook delivery %d does not exist.\n", id)
+	case err != nil:
+		fmt.Fprintf(writer, "Error getting delivery: %v\n", err)
+	default:
+		fmt.Fprintf(writer, "Webhook delivery %d is already pending.\n", id)
+	}
+}
+
+func parseWebhookDeliveryID(args discord.DiscordMessageArgs, writer io.Writer) (int64, bool) {
+	rest := args.Args
+	if len(rest) != 1 {
+		fmt.Fprintf(writer, "Usage: %s <id>\n", args.CommandName)
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(rest[0], "#"), 10, 64)
+	if err != nil || id <= 0 {
+		fmt.Fprintf(writer, "Invalid delivery id: %s\n", rest[0])
+		return 0, false
+	}
+	return id, true
+}
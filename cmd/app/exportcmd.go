@@ -0,0 +1,125 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/export"
+	"TwitchDonoCalculator/internal/validator"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ExportArgs struct {
+	Format export.Format
+	Filter export.Filter
+	validator.Validator
+}
+
+// exportCommand declares the flags shared by the export command and its
+// Discord counterpart.
+var exportCommand = discord.Command{
+	Name:       "export",
+	Summary:    "Export donations.",
+	Permission: discord.LevelModerator,
+	Flags: []discord.Flag{
+		{Name: "format", Default: "csv", Usage: "csv, ndjson or parquet"},
+		{Name: "channel", Usage: "only donations for this channel, all channels when empty"},
+		{Name: "donor", Usage: "only donations sent by this donor"},
+		{Name: "from", Usage: dateFromUsage},
+		{Name: "to", Usage: dateToUsage},
+	},
+}
+
+// parseExportArgs reads the flags of exportCommand, invalid values are
+// reported through the returned validator.
+func (app *application) parseExportArgs(v *discord.Values) *ExportArgs {
+	argsStruct := &ExportArgs{}
+	var err error
+	argsStruct.Format, err = export.ParseFormat(strings.ToLower(v.String("format")))
+	if err != nil {
+		argsStruct.AddFieldError("format", "Format must be one of csv, ndjson or parquet.")
+	}
+	if channel := v.String("channel"); channel != "" {
+		argsStruct.Filter.Channel = normalizeChannel(channel)
+		argsStruct.CheckField(config.ValidChannel(argsStruct.Filter.Channel), "channel", "Channel must be a Twitch login, letters, digits and underscores.")
+	}
+	argsStruct.Filter.Donor = v.String("donor")
+	app.handleDateRange(&argsStruct.Validator, argsStruct.Filter.Channel, v.String("from"), v.String("to"), &argsStruct.Filter.From, &argsStruct.Filter.To)
+	return argsStruct
+}
+
+// runExport implements the "export" command, it writes donations to -out or
+// stdout.
+func runExport(configPath string, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	database, err := db.OpenDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer database.Close()
+	db.RunMigrations(database)
+
+	app := &application{
+		db:  db.New(database),
+		cfg: cfg,
+	}
+
+	cmd := exportCommand
+	cmd.Flags = append(slices.Clone(cmd.Flags), discord.Flag{Name: "out", Usage: "file to write, stdout when empty"})
+	v, err := cmd.Parse(args, os.Stderr)
+	if err != nil {
+		return err
+	}
+	argsStruct := app.parseExportArgs(v)
+	if !argsStruct.Valid() {
+		return &argsStruct.Validator
+	}
+
+	out := v.String("out")
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	written, err := app.exportDonations(context.Background(), argsStruct, w)
+	if err != nil {
+		if out != "" {
+			os.Remove(out)
+		}
+		return fmt.Errorf("export failed: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d donations.\n", written)
+	return nil
+}
+
+func (app *application) exportDonations(ctx context.Context, argsStruct *ExportArgs, w io.Writer) (int, error) {
+	ew := export.NewWriter(argsStruct.Format, w)
+	written, err := export.Donations(ctx, app.db, argsStruct.Filter, ew)
+	if err != nil {
+		return written, err
+	}
+	return written, ew.Close()
+}
+
+// exportFileName names an export file after its channel and creation time.
+func exportFileName(argsStruct *ExportArgs, now time.Time) string {
+	channel := "all"
+	if argsStruct.Filter.Channel != "" {
+		channel = argsStruct.Filter.Channel[1:]
+	}
+	return fmt.Sprintf("donations-%s-%s.%s", channel, now.Format("20060102-150405"), argsStruct.Format.Extension())
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/export"
+	"TwitchDonoCalculator/internal/feed"
+	"TwitchDonoCalculator/internal/validator"
+	"TwitchDonoCalculator/overlay"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// feedHeartbeat keeps idle feed connections open through proxies.
+const feedHeartbeat = 15 * time.Second
+
+// feedStateType events carry the overlay state after every donation.
+const feedStateType = "state"
+
+type overlayGoal struct {
+	Title    string `json:"title"`
+	Raised   int64  `json:"raised"`
+	Target   int64  `json:"target"`
+	Currency string `json:"currency"`
+}
+
+type overlayDonor struct {
+	Donor string `json:"donor"`
+	Total int64  `json:"total"`
+}
+
+// overlayState is what the overlay shows besides the latest donation: the
+// newest open goal and the top donor of the current or last stream.
+type overlayState struct {
+	Goal *overlayGoal  `json:"goal"`
+	Top  *overlayDonor `json:"top"`
+}
+
+// loadFeed primes the replay buffer of a channel from the database.
+func (app *application) loadFeed(ctx context.Context, channel string, n int) ([]feed.Event, error) {
+	rows, err := app.db.ListLastDonations(ctx, db.ListLastDonationsParams{
+		Channel: channel,
+		// a little ahead so clock skew with SQLite can't hide the newest
+		Until:    time.Now().Add(time.Hour),
+		RowLimit: int64(n),
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]feed.Event, len(rows))
+	for i, d := range rows {
+		events[len(rows)-1-i] = donationEvent(d)
+	}
+	return events, nil
+}
+
+func donationEvent(d db.Donation) feed.Event {
+	return feed.Event{Type: feed.TypeDonation, ID: d.ID, Data: export.NewRow(d)}
+}
+
+// publishDonation sends a new donation and the updated overlay state to the
+// live feed of its channel.
+func (app *application) publishDonation(d db.Donation) {
+	if app.feed == nil {
+		return
+	}
+	app.feed.Publish(d.Channel, donationEvent(d))
+	state, err := app.overlayState(context.Background(), d.Channel)
+	if err != nil {
+		app.logger.Error("failed to get overlay state", "channel", d.Channel, "error", err)
+		return
+	}
+	app.feed.Publish(d.Channel, feed.Event{Type: feedStateType, Data: state})
+}
+
+func (app *application) overlayState(ctx context.Context, channel string) (overlayState, error) {
+	var state overlayState
+	goals, err := app.db.ListGoalProgress(ctx, db.ListGoalProgressParams{
+		Channel:  channel,
+		RowLimit: 1,
+	})
+	if err != nil {
+		return state, err
+	}
+	if len(goals) > 0 {
+		g := goals[0]
+		state.Goal = &overlayGoal{Title: g.Title, Raised: g.Raised, Target: g.Target, Currency: g.Currency}
+	}
+
+	from, to, err := app.lastStream(ctx, channel)
+	if errors.Is(err, errNoStream) {
+		from, to, _ = validator.RelativeRange("today", time.Now().In(app.cfg.Location))
+	} else if err != nil {
+		return state, err
+	}
+	donors, err := app.db.ListTopDonors(ctx, db.ListTopDonorsParams{
+		Channel:       channel,
+		FromTimestamp: from,
+		ToTimestamp:   to.Add(time.Second),
+		OrderBy:       "total",
+		RowLimit:      1,
+	})
+	if err != nil {
+		return state, err
+	}
+	if len(donors) > 0 {
+		state.Top = &overlayDonor{Donor: donors[0].Donor, Total: donors[0].Total}
+	}
+	return state, nil
+}
+
+// apiFeed streams donations of a channel as Server-Sent Events. Kept
+// donations newer than Last-Event-ID, at most ?replay=N of them, are sent
+// first as "replay" events, followed by the current "state" and then live
+// "donation" and "state" events.
+func (app *application) apiFeed(w http.ResponseWriter, r *http.Request) {
+	channel := normalizeChannel(r.PathValue("channel"))
+	v := &validator.Validator{}
+	v.CheckField(config.ValidChannel(channel), "channel", "Channel must be a Twitch login, letters, digits and underscores.")
+	replayLimit := queryInt64(v, r.URL.Query(), "replay", int64(app.cfg.HTTP.FeedReplay))
+	v.CheckField(replayLimit >= 0, "replay", "Replay must not be negative.")
+	var lastID int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		var err error
+		lastID, err = strconv.ParseInt(header, 10, 64)
+		v.CheckField(err == nil, "Last-Event-ID", "Last-Event-ID must be a donation id.")
+	}
+	if !v.Valid() {
+		app.apiValidationError(w, v)
+		return
+	}
+
+	replay, events, cancel, err := app.feed.Subscribe(r.Context(), channel, lastID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	defer cancel()
+	replay = replay[len(replay)-min(len(replay), int(replayLimit)):]
+
+	state, err := app.overlayState(r.Context(), channel)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	// the feed outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range replay {
+		e.Type = "replay"
+		if err := writeFeedEvent(w, e); err != nil {
+			return
+		}
+	}
+	if err := writeFeedEvent(w, feed.Event{Type: feedStateType, Data: state}); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(feedHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-events:
+			if !ok {
+				// too slow, the client reconnects with Last-Event-ID
+				return
+			}
+			if err := writeFeedEvent(w, e); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeFeedEvent(w http.ResponseWriter, e feed.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	if e.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", e.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
+
+// serveOverlay serves the embedded overlay page, it reads the channel from
+// its own URL.
+func (app *application) serveOverlay(w http.ResponseWriter, r *http.Request) {
+	page, err := overlay.OverlayFiles.ReadFile("overlay.html")
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
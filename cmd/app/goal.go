@@ -0,0 +1,79 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"context"
+	"fmt"
+)
+
+// goalMilestones are the percentages of a goal announced on Discord.
+var goalMilestones = []int64{25, 50, 75, 100}
+
+// maxOpenGoals bounds how many open goals of a channel are checked after
+// each donation.
+const maxOpenGoals = 100
+
+// checkGoals announces every open goal of channel that crossed a new
+// milestone. Only the highest milestone is announced when a single donation
+// crosses several.
+func (app *application) checkGoals(channel string) {
+	ctx := context.Background()
+	goals, err := app.db.ListGoalProgress(ctx, db.ListGoalProgressParams{
+		Channel:  channel,
+		RowLimit: maxOpenGoals,
+	})
+	if err != nil {
+		app.logger.Error("failed to get goals", "channel", channel, "error", err)
+		return
+	}
+
+	for _, g := range goals {
+		milestone := reachedMilestone(g.Raised, g.Target)
+		if milestone <= g.LastMilestone {
+			continue
+		}
+		// the conditional update makes sure every milestone is announced once
+		updated, err := app.db.SetGoalMilestone(ctx, db.SetGoalMilestoneParams{
+			Milestone: milestone,
+			ID:        g.ID,
+		})
+		if err != nil {
+			app.logger.Error("failed to update goal milestone", "goal", g.ID, "error", err)
+			continue
+		}
+		if updated == 0 {
+			continue
+		}
+		fmt.Fprintf(app.discord.Alerts(g.Channel), "%s goal %s reached %d%%: %s", g.Channel, goalName(g), milestone, goalProgress(g))
+	}
+}
+
+// reachedMilestone returns the highest milestone raised has reached, 0 for
+// none.
+func reachedMilestone(raised, target int64) int64 {
+	if target <= 0 {
+		return 0
+	}
+	for i := len(goalMilestones) - 1; i >= 0; i-- {
+		if raised*100 >= goalMilestones[i]*target {
+			return goalMilestones[i]
+		}
+	}
+	return 0
+}
+
+func goalName(g db.ListGoalProgressRow) string {
+	if g.Title == "" {
+		return fmt.Sprintf("#%d", g.ID)
+	}
+	return fmt.Sprintf("#%d %q", g.ID, g.Title)
+}
+
+// goalProgress prints "2500/5000 CZK (50%)".
+func goalProgress(g db.ListGoalProgressRow) string {
+	progress := fmt.Sprintf("%d/%d", g.Raised, g.Target)
+	if g.Currency != "" {
+		progress += " " + g.Currency
+	}
+	return fmt.Sprintf("%s (%d%%)", progress, g.Raised*100/g.Target)
+}
@@ -2,64 +2,108 @@ package main
 
 import (
 	"TwitchDonoCalculator/internal/db"
-	"TwitchDonoCalculator/internal/discord"
 	"TwitchDonoCalculator/internal/twitch"
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 )
 
+// donationLimitNotification is the default amount from which a donation is
+// announced on Discord.
 const donationLimitNotification = 10_000
 
 func (app *application) HandleAnyMessage(m twitch.Message) {
+	app.health.TwitchActivity()
 	app.LogAnyMessage(m.GetRaw())
 }
 
 
 
 func (app *application) HandleChatMessage(m *twitch.MessagePrivate) {
-	streamer := app.streamers[m.Streamer]
+	streamer := app.GetStreamer(m.Streamer)
+	if streamer == nil {
+		return
+	}
 	app.LogStreamerMessage(m, streamer)
+	app.health.ChannelActivity(m.Streamer)
+	sessionID := app.touchSession(m.Streamer)
 	if streamer.BotName != m.Sender {
 		return
 	}
 
 	value := streamer.FindDonation(m.Text)
 	if value == 0 {
+		// an empty filter matches every message of the bot, not only donations
+		if streamer.LineFilterContain != "" && strings.Contains(m.Text, streamer.LineFilterContain) {
+			app.health.Alert("regex:"+m.Streamer, fmt.Sprintf("Donation message in %s does not match the regex %s: %s", m.Streamer, streamer.RegFind, m.Text))
+		}
 		return
 	}
-	if value >= donationLimitNotification {
-		fmt.Fprintf(discord.DefaultServer, "%s just got  %d donation", m.Streamer, value)
+	if value >= streamer.NotifyThreshold {
+		fmt.Fprintf(app.discord.Alerts(m.Streamer), "%s just got  %d donation", m.Streamer, value)
 	}
 
-	app.db.CreateDonation(context.Background(), db.CreateDonationParams{
-		User:     m.Sender,
-		Channel:  m.Streamer,
-		SendFrom: strings.Split(m.Text, " ")[0],
-		Amount:   value,
-		Text:     m.Text,
+	app.saveDonation(db.CreateDonationParams{
+		User:      m.Sender,
+		Channel:   m.Streamer,
+		SendFrom:  strings.Split(m.Text, " ")[0],
+		Amount:    value,
+		Text:      m.Text,
+		SessionID: sessionID,
 	})
 
 }
 
 func (app *application) HandleChatNotice(m *twitch.MessageNotice) {
-	streamer := app.streamers[m.Streamer]
+	streamer := app.GetStreamer(m.Streamer)
+	if streamer == nil {
+		return
+	}
+	app.health.ChannelActivity(m.Streamer)
+	sessionID := app.touchSession(m.Streamer)
 	value := streamer.FindDonation(m.Text)
 	if value == 0 {
 		return
 	}
-	if value >= donationLimitNotification {
-		fmt.Fprintf(discord.DefaultServer, "%s just got  %d donation", m.Streamer, value)
+	if value >= streamer.NotifyThreshold {
+		fmt.Fprintf(app.discord.Alerts(m.Streamer), "%s just got  %d donation", m.Streamer, value)
 	}
-	app.db.CreateDonation(context.Background(), db.CreateDonationParams{
-		User:     "",
-		Channel:  m.Streamer,
-		SendFrom: strings.Split(m.Text, " ")[0],
-		Amount:   value,
-		Text:     m.Text,
+	app.saveDonation(db.CreateDonationParams{
+		User:      "",
+		Channel:   m.Streamer,
+		SendFrom:  strings.Split(m.Text, " ")[0],
+		Amount:    value,
+		Text:      m.Text,
+		SessionID: sessionID,
 	})
 }
 
+// saveDonation stores a donation, updates the goals of its channel and
+// publishes it to the live feed and the webhooks.
+func (app *application) saveDonation(params db.CreateDonationParams) {
+	donation, err := app.db.CreateDonation(context.Background(), params)
+	if err != nil {
+		app.logger.Error("failed to save donation", "channel", params.Channel, "error", err)
+		app.health.Alert("db:donation", fmt.Sprintf("Failed to save a donation of %d in %s: %v", params.Amount, params.Channel, err))
+		return
+	}
+	app.checkGoals(params.Channel)
+	app.publishDonation(donation)
+	app.enqueueWebhooks(donation)
+}
+
+// touchSession records chat activity in channel and returns the stream
+// session donations should be linked to, null when there is none.
+func (app *application) touchSession(channel string) sql.NullInt64 {
+	id, err := app.sessions.Touch(context.Background(), channel)
+	if err != nil {
+		app.logger.Error("failed to update stream session", "channel", channel, "error", err)
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
 func (app *application) HandleUnknowMessage(m *twitch.UnknowMessage) {
 	app.LogUnknownMessage(m.Raw)
 }
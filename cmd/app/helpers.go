@@ -1,67 +1,95 @@
 package main
 
 import (
-	"TwitchDonoCalculator/internal/discord"
 	"TwitchDonoCalculator/internal/twitch"
 	"fmt"
 	"os"
 	"path"
+	"strings"
 )
 
 func (app *application) GetStreamer(streamer string) *Streamer {
+	app.streamersMu.RLock()
+	defer app.streamersMu.RUnlock()
 	return app.streamers[streamer]
 }
 
+// StreamerChannels returns the channels currently tracked.
+func (app *application) StreamerChannels() []string {
+	app.streamersMu.RLock()
+	defer app.streamersMu.RUnlock()
+	channels := make([]string, 0, len(app.streamers))
+	for channel := range app.streamers {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// normalizeChannel turns "Tartancz" or "#tartancz" into the "#tartancz" form
+// used as key for streamers.
+func normalizeChannel(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "#") {
+		name = "#" + name
+	}
+	return name
+}
+
 func (app *application) LogStreamerMessage(message twitch.Message, streamer *Streamer) {
 	if !streamer.LogMessage {
 		return
 	}
-
-	//check if folder exists
-	app.CreateLogFolder()
-	if streamer.LogFile == nil {
-		if file, err := app.CreateLogFile(streamer.ChannelName); err != nil {
-			fmt.Fprintf(discord.DefaultServer,"ERROR when creating log file: %s", err)
-			fmt.Println(err)
-			return
-		} else {
-			streamer.LogFile = file
-		}
-	}
-	fmt.Fprintln(streamer.LogFile, message.GetRaw())
+	app.writeLog(streamer.ChannelName, message.GetRaw())
 }
 
 func (app *application) LogUnknownMessage(RawMessage string) {
 	if !app.cfg.LogUnknownMessage {
 		return
 	}
-	app.CreateLogFolder()
-	if app.unknowLogFile == nil {
-		if file, err := app.CreateLogFile("unknown"); err != nil {
-			fmt.Fprintf(discord.DefaultServer,"ERROR when creating log file: %s", err)
-			return
-		} else {
-			app.unknowLogFile = file
-		}
-	}
-	fmt.Fprintln(app.unknowLogFile, RawMessage)
+	app.writeLog("unknown", RawMessage)
 }
 
 func (app *application) LogAnyMessage(RawMessage string) {
 	if !app.cfg.LogAll {
 		return
 	}
-	app.CreateLogFolder()
-	if app.allLogFile == nil {
-		if file, err := app.CreateLogFile("all"); err != nil {
-			fmt.Fprintf(discord.DefaultServer,"ERROR when creating log file: %s", err)
-			return
-		} else {
-			app.allLogFile = file
+	app.writeLog("all", RawMessage)
+}
+
+// writeLog appends line to the log file called name, opening it on first use.
+// Messages are handled concurrently, so log files are only opened, written
+// and closed under logMu.
+func (app *application) writeLog(name string, line string) {
+	app.logMu.Lock()
+	file, ok := app.logFiles[name]
+	var err error
+	if !ok {
+		app.CreateLogFolder()
+		if file, err = app.CreateLogFile(name); err == nil {
+			if app.logFiles == nil {
+				app.logFiles = make(map[string]*os.File)
+			}
+			app.logFiles[name] = file
 		}
 	}
-	fmt.Fprintln(app.allLogFile, RawMessage)
+	if err == nil {
+		fmt.Fprintln(file, line)
+	}
+	app.logMu.Unlock()
+
+	if err != nil {
+		app.health.Alert("logfile", fmt.Sprintf("ERROR when creating log file: %s", err))
+	}
+}
 
+// closeLog closes the log file called name, a later message opens it again.
+func (app *application) closeLog(name string) {
+	app.logMu.Lock()
+	defer app.logMu.Unlock()
+	if file, ok := app.logFiles[name]; ok {
+		file.Close()
+		delete(app.logFiles, name)
+	}
 }
 
 func (app *application) CreateLogFolder() {
@@ -79,12 +107,10 @@ func (app *application) CreateLogFile(fileName string) (*os.File, error) {
 }
 
 func (app *application) CloseLogFiles() {
-	for _, streamer := range app.streamers {
-		if streamer.LogFile != nil {
-			streamer.LogFile.Close()
-		}
-	}
-	if app.unknowLogFile != nil {
-		app.unknowLogFile.Close()
+	app.logMu.Lock()
+	defer app.logMu.Unlock()
+	for name, file := range app.logFiles {
+		file.Close()
+		delete(app.logFiles, name)
 	}
 }
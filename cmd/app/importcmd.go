@@ -0,0 +1,105 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/importer"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rateFlag collects repeated -rate EUR=25 flags.
+type rateFlag map[string]float64
+
+func (r rateFlag) String() string {
+	parts := make([]string, 0, len(r))
+	for currency, rate := range r {
+		parts = append(parts, fmt.Sprintf("%s=%g", currency, rate))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r rateFlag) Set(value string) error {
+	currency, rateValue, found := strings.Cut(value, "=")
+	if !found {
+		return errors.New("use CURRENCY=rate, e.g. EUR=25")
+	}
+	rate, err := strconv.ParseFloat(rateValue, 64)
+	if err != nil || rate <= 0 {
+		return fmt.Errorf("invalid rate %q", rateValue)
+	}
+	r[strings.ToUpper(currency)] = rate
+	return nil
+}
+
+// runImport implements the "import" command that loads historical donations
+// from CSV exports of other bots.
+func runImport(configPath string, args []string, out io.Writer) error {
+	f := flag.NewFlagSet("import", flag.ContinueOnError)
+	profileName := f.String("profile", "generic", "column profile: "+strings.Join(importer.ProfileNames(), ", "))
+	mapping := f.String("map", "", "override columns, e.g. amount=Total,donor=From")
+	file := f.String("file", "", "CSV file to import")
+	channel := f.String("channel", "", "channel for files without a channel column")
+	currency := f.String("currency", "CZK", "currency donations are stored in")
+	rates := rateFlag{}
+	f.Var(rates, "rate", "conversion rate into -currency, e.g. EUR=25, can be repeated")
+	dryRun := f.Bool("dry-run", false, "validate and count duplicates without importing")
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+	profile, err := importer.GetProfile(*profileName, *mapping)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	in, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	opts := importer.Options{
+		Currency: strings.ToUpper(*currency),
+		Rates:    rates,
+		Location: cfg.Location,
+	}
+	if *channel != "" {
+		opts.Channel = normalizeChannel(*channel)
+	}
+	records, v := importer.Read(in, profile, opts)
+	if !v.Valid() {
+		return fmt.Errorf("%s is invalid, nothing was imported:\n%w", *file, v)
+	}
+
+	database, err := db.OpenDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer database.Close()
+	db.RunMigrations(database)
+
+	result, err := importer.Import(context.Background(), database, records, profile.Name, *dryRun)
+	if err != nil {
+		return fmt.Errorf("import failed, nothing was imported: %w", err)
+	}
+	if *dryRun {
+		fmt.Fprintf(out, "Dry run: %d of %d donations would be imported, %d are duplicates.\n", result.Imported, len(records), result.Duplicates)
+		return nil
+	}
+	fmt.Fprintf(out, "Imported %d of %d donations, skipped %d duplicates.\n", result.Imported, len(records), result.Duplicates)
+	return nil
+}
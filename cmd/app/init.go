@@ -0,0 +1,41 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runInit is the opt-in replacement for the old interactive fallback: it asks
+// for the first streamer and writes a complete config file to configPath.
+func runInit(configPath string, args []string) error {
+	f := flag.NewFlagSet("init", flag.ContinueOnError)
+	force := f.Bool("force", false, "overwrite an existing config file")
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(configPath); err == nil && !*force {
+		return fmt.Errorf("%s already exists, use -force to overwrite it", configPath)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	cfg := config.Default()
+	channel, streamer, err := config.PromptStreamerConfig(os.Stdin, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to read streamer config: %w", err)
+	}
+	cfg.Streamers[channel] = streamer
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+	if err := config.WriteFile(configPath, cfg); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Printf("Config written to %s\n", configPath)
+	return nil
+}
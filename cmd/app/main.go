@@ -4,60 +4,199 @@ import (
 	"TwitchDonoCalculator/internal/config"
 	"TwitchDonoCalculator/internal/db"
 	"TwitchDonoCalculator/internal/discord"
+	"TwitchDonoCalculator/internal/feed"
+	"TwitchDonoCalculator/internal/health"
+	"TwitchDonoCalculator/internal/session"
 	"TwitchDonoCalculator/internal/twitch"
+	"TwitchDonoCalculator/internal/webhook"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+	_ "time/tzdata"
 
 	_ "github.com/joho/godotenv/autoload"
 )
 
+// sessionPollInterval is how often stream sessions are checked for an end.
+const sessionPollInterval = time.Minute
+
+// webhookPollInterval is how often webhook retries are checked for being due.
+const webhookPollInterval = 15 * time.Second
+
+// healthPollInterval is how often the health monitor checks the pipeline.
+const healthPollInterval = time.Minute
+
 type application struct {
-	db            *db.Queries
-	streamers     map[string]*Streamer
-	cfg           *config.Config
-	logger        *slog.Logger
-	unknowLogFile *os.File
-	allLogFile    *os.File
+	db          *db.Queries
+	database    *sql.DB
+	twitch      *twitch.Client
+	discord     discord.Backend
+	sessions    *session.Tracker
+	feed        *feed.Hub
+	webhooks    *webhook.Outbox
+	health      *health.Monitor
+	reloadMu    sync.Mutex
+	streamersMu sync.RWMutex
+	streamers   map[string]*Streamer
+	cfg         *config.Config
+	logger      *slog.Logger
+	// logMu guards logFiles, the open chat log files by name.
+	logMu    sync.Mutex
+	logFiles map[string]*os.File
 }
 
 func main() {
-	go discord.DefaultServer.RunServer("TwitchDonoCalculator")
-	defer discord.DefaultServer.Close()
-	cfg := config.Load()
+	configPath := flag.String("config", getEnv("CONFIG_PATH", config.DefaultPath), "path to the JSON config file")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	var err error
+	switch command := flag.Arg(0); command {
+	case "", "run":
+		err = run(*configPath)
+	case "init":
+		err = runInit(*configPath, flag.Args()[1:])
+	case "config":
+		err = runConfig(*configPath, flag.Args()[1:])
+	case "export":
+		err = runExport(*configPath, flag.Args()[1:])
+	case "import":
+		err = runImport(*configPath, flag.Args()[1:], os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(configPath string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var backend discord.Backend
+	var bot *discord.Bot
+	var server *discord.Server
+	switch cfg.Discord.Backend {
+	case config.DiscordBackendBot:
+		bot, err = discord.NewBot(cfg.Discord.Bot, &http.Client{Timeout: 30 * time.Second}, slog.Default())
+		if err != nil {
+			return err
+		}
+		backend = bot
+	default:
+		server = discord.NewServer()
+		backend = server
+	}
+	defer backend.Close()
 
 	// Initialize database
 	database, err := db.OpenDB(cfg.DB)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer database.Close()
 	db.RunMigrations(database)
 
 	c := twitch.NewAnonymousClient()
 
+	var liveSource session.LiveStatusSource
+	if cfg.Session.Source == config.SessionSourceHelix {
+		liveSource = session.NewHelixSource(cfg.Session, &http.Client{Timeout: 10 * time.Second})
+	}
+
 	var app = &application{
-		db:        db.New(database),
-		streamers: NewStreamersFromMap(cfg.Streamers),
-		cfg:       cfg,
-		logger:    slog.Default(),
+		db:       db.New(database),
+		database: database,
+		twitch:   c,
+		discord:  backend,
+		sessions: session.NewTracker(db.New(database), time.Duration(cfg.Session.Gap), liveSource),
+		health:   health.NewMonitor(cfg.Health, backend.Alerts(discord.OpsChannel), slog.Default()),
+		cfg:      cfg,
+		logger:   slog.Default(),
 	}
+	app.feed = feed.NewHub(cfg.HTTP.FeedReplay, app.loadFeed)
+	app.webhooks = webhook.NewOutbox(app.db, webhookTargets(cfg.Webhooks), &http.Client{}, app.webhookDead)
 	defer app.CloseLogFiles()
 
+	if err := app.StartStreamers(ctx); err != nil {
+		return err
+	}
+
+	// every command and the guard are in place before anything can dispatch
+	app.registerDiscordCommands()
+	if server != nil {
+		go server.RunServer(ctx, "TwitchDonoCalculator", cfg.Discord)
+	}
+	go app.WatchStreamersFile(ctx)
+	go app.sessions.Run(ctx, sessionPollInterval, app.StreamerChannels, app.logger)
+	go app.webhooks.Run(ctx, webhookPollInterval, app.logger)
+	go app.health.Run(ctx, healthPollInterval, app.StreamerChannels)
+	go func() {
+		if err := app.ServeAPI(ctx); err != nil {
+			app.logger.Error("API server stopped", "error", err)
+		}
+	}()
+
 	c.SetOnChatMessage(app.HandleChatMessage)
 	c.SetOnChatNotice(app.HandleChatNotice)
 	c.SetOnAnyMessage(app.HandleAnyMessage)
 	c.SetOnUnknowMessage(app.HandleUnknowMessage)
+	c.SetOnConnect(app.health.TwitchConnected)
+	c.SetOnDisconnect(app.health.TwitchDisconnected)
 
-	app.registerDiscordCommands()
+	if bot != nil {
+		// registers the slash commands, so only once every handler is added
+		go bot.Run(ctx)
+	}
 
-	for k := range cfg.Streamers {
+	for k := range app.streamers {
 		c.AddStreamers(k)
 	}
 
-	err = c.Listen()
-	if err != nil {
-		app.logger.Error(err.Error())
-		return
+	return c.Listen()
+}
+
+var commandsUsage = [][2]string{
+	{"run", "start watching chat (default)"},
+	{"init [-force]", "interactively create a config file"},
+	{"config validate", "load the config and compile every regex"},
+	{"config test-regex -channel X [-file log]", "show donations found in sample lines from stdin or a log file"},
+	{"config dump", "print the effective config with secrets redacted"},
+	{"export [-format csv|ndjson|parquet] [-out file]", "export donations, filter with -channel, -donor, -from and -to"},
+	{"import -file X [-profile P] [-dry-run]", "import donations from a CSV export of another bot"},
+}
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [-config path] [command]\n\nCommands:\n", os.Args[0])
+	tb := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, c := range commandsUsage {
+		fmt.Fprintf(tb, "  %s\t%s\n", c[0], c[1])
+	}
+	tb.Flush()
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
 	}
+	return defaultValue
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/twitch"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const streamersPollInterval = 5 * time.Second
+
+type streamersDiff struct {
+	Added   []string
+	Removed []string
+	Updated []string
+}
+
+func (d streamersDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0
+}
+
+func (d streamersDiff) String() string {
+	parts := make([]string, 0, 3)
+	if len(d.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(d.Added, ", "))
+	}
+	if len(d.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(d.Removed, ", "))
+	}
+	if len(d.Updated) > 0 {
+		parts = append(parts, "updated: "+strings.Join(d.Updated, ", "))
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// WatchStreamersFile imports the streamers from the config file into the
+// database whenever the file changes and then reloads them. Other settings
+// still need a restart.
+func (app *application) WatchStreamersFile(ctx context.Context) {
+	source := app.cfg.Source
+	if source == "" {
+		return
+	}
+	config.WatchFile(ctx, source, streamersPollInterval, func() {
+		diff, err := app.importAndReload(ctx)
+		if err != nil {
+			app.logger.Error("failed to reload streamers", "error", err)
+			fmt.Fprintf(app.discord, "Failed to reload %s, keeping previous config:\n%s", source, err)
+			return
+		}
+		if diff.Empty() {
+			return
+		}
+		app.logger.Info("reloaded streamers", "changes", diff.String())
+		fmt.Fprintf(app.discord, "Reloaded %s: %s", source, diff)
+	})
+}
+
+func (app *application) importAndReload(ctx context.Context) (streamersDiff, error) {
+	cfg, err := config.Load(app.cfg.Path)
+	if err != nil {
+		return streamersDiff{}, err
+	}
+	if _, err := app.ImportConfigStreamers(ctx, cfg.Streamers); err != nil {
+		return streamersDiff{}, err
+	}
+	return app.ReloadStreamers(ctx)
+}
+
+// ReloadStreamers loads and validates the enabled streamers from the database
+// and only then swaps the new set in, joining and parting Twitch channels as
+// needed. On any error, including a failed join or part, the running set is
+// left untouched.
+func (app *application) ReloadStreamers(ctx context.Context) (streamersDiff, error) {
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
+
+	next, err := app.LoadStreamers(ctx)
+	if err != nil {
+		return streamersDiff{}, err
+	}
+	if len(next) > twitch.MaxStreamers {
+		return streamersDiff{}, fmt.Errorf("%d enabled streamers: %w", len(next), twitch.ErrTooMuchStreamers)
+	}
+
+	var diff streamersDiff
+	app.streamersMu.Lock()
+	previous := app.streamers
+	for channel, streamer := range next {
+		current, exists := previous[channel]
+		if !exists {
+			diff.Added = append(diff.Added, channel)
+			continue
+		}
+		if !current.sameConfig(streamer) {
+			diff.Updated = append(diff.Updated, channel)
+		}
+	}
+	for channel := range previous {
+		if _, exists := next[channel]; !exists {
+			diff.Removed = append(diff.Removed, channel)
+		}
+	}
+	app.streamers = next
+	app.streamersMu.Unlock()
+
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+	slices.Sort(diff.Updated)
+
+	if err := app.applyChannels(diff.Added, diff.Removed); err != nil {
+		app.streamersMu.Lock()
+		app.streamers = previous
+		app.streamersMu.Unlock()
+		// the client forgets or remembers the channels before it writes, so
+		// this restores its list even when the connection is down
+		app.twitch.Part(diff.Added...)
+		app.twitch.Join(diff.Removed...)
+		return streamersDiff{}, err
+	}
+
+	for channel := range previous {
+		if kept, exists := next[channel]; !exists || !kept.LogMessage {
+			app.closeLog(channel)
+		}
+	}
+	return diff, nil
+}
+
+// applyChannels joins added and parts removed Twitch channels.
+func (app *application) applyChannels(added, removed []string) error {
+	if len(added) > 0 {
+		if err := app.twitch.Join(added...); err != nil {
+			return fmt.Errorf("failed to join channels: %w", err)
+		}
+	}
+	if len(removed) > 0 {
+		if err := app.twitch.Part(removed...); err != nil {
+			return fmt.Errorf("failed to leave channels: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/twitch"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestReloadStreamers(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	sc := config.StreamerConfig{BotName: "bot", ValueRegex: `(\d+)`}
+	for _, channel := range []string{"#tartancz", "#other"} {
+		if _, err := app.SaveStreamer(ctx, channel, sc, configChangedBy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := app.ReloadStreamers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"#other", "#tartancz"}; !slices.Equal(diff.Added, want) {
+		t.Errorf("added %v, want %v", diff.Added, want)
+	}
+
+	disabled := sc
+	disabled.Disabled = true
+	if _, err := app.SaveStreamer(ctx, "#other", disabled, discordChangedBy); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = app.ReloadStreamers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"#other"}; !slices.Equal(diff.Removed, want) || len(diff.Added) > 0 {
+		t.Errorf("diff %v, want removed %v", diff, want)
+	}
+}
+
+func TestReloadStreamersRejectsTooManyChannels(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	sc := config.StreamerConfig{BotName: "bot", ValueRegex: `(\d+)`}
+	for i := range twitch.MaxStreamers + 1 {
+		if _, err := app.SaveStreamer(ctx, fmt.Sprintf("#channel%d", i), sc, configChangedBy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := app.ReloadStreamers(ctx); !errors.Is(err, twitch.ErrTooMuchStreamers) {
+		t.Fatalf("ReloadStreamers() error = %v, want ErrTooMuchStreamers", err)
+	}
+	if channels := app.StreamerChannels(); len(channels) > 0 {
+		t.Errorf("tracked %v after a rejected reload", channels)
+	}
+}
+
+func TestReloadStreamersRollsBackFailedJoin(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	sc := config.StreamerConfig{BotName: "bot", ValueRegex: `(\d+)`}
+	if _, err := app.SaveStreamer(ctx, "#tartancz", sc, configChangedBy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.ReloadStreamers(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// fill the client up so joining the next channel fails
+	full := []string{"#tartancz"}
+	for i := range twitch.MaxStreamers - 1 {
+		full = append(full, fmt.Sprintf("#joined%d", i))
+	}
+	if err := app.twitch.SetStreamers(full...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SaveStreamer(ctx, "#new", sc, configChangedBy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := app.ReloadStreamers(ctx); !errors.Is(err, twitch.ErrTooMuchStreamers) {
+		t.Fatalf("ReloadStreamers() error = %v, want ErrTooMuchStreamers", err)
+	}
+	if channels := app.StreamerChannels(); !slices.Equal(channels, []string{"#tartancz"}) {
+		t.Errorf("tracked %v after a failed join, want the previous set", channels)
+	}
+}
+
+func TestReloadStreamersWhileLogging(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.cfg.LogFolder = t.TempDir()
+	t.Cleanup(app.CloseLogFiles)
+	ctx := context.Background()
+	sc := config.StreamerConfig{BotName: "bot", ValueRegex: `(\d+)`, LogMessage: true}
+	if _, err := app.SaveStreamer(ctx, "#tartancz", sc, configChangedBy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.ReloadStreamers(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	message := twitch.ParseMessage(":bot!bot@bot.tmi.twitch.tv PRIVMSG #tartancz :sent 100")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 200 {
+			if streamer := app.GetStreamer("#tartancz"); streamer != nil {
+				app.LogStreamerMessage(message, streamer)
+			}
+		}
+	}()
+	for i := range 20 {
+		sc.LogMessage = i%2 == 1
+		if _, err := app.SaveStreamer(ctx, "#tartancz", sc, configChangedBy); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := app.ReloadStreamers(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
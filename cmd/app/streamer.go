@@ -2,7 +2,9 @@ package main
 
 import (
 	"TwitchDonoCalculator/internal/config"
-	"os"
+	"TwitchDonoCalculator/internal/db"
+	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -14,25 +16,78 @@ type Streamer struct {
 	RegFind           *regexp.Regexp
 	LineFilterContain string
 	LogMessage        bool
-	LogFile           *os.File
+	NotifyThreshold   int64
 }
 
-func NewStreamer(streamerConfig config.StreamerConfig, channelName string) *Streamer {
+func NewStreamer(streamerConfig config.StreamerConfig, channelName string) (*Streamer, error) {
+	regFind, err := regexp.Compile(streamerConfig.ValueRegex)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid ValueRegex: %w", channelName, err)
+	}
+	notifyThreshold := streamerConfig.NotifyThreshold
+	if notifyThreshold == 0 {
+		notifyThreshold = donationLimitNotification
+	}
 	return &Streamer{
 		BotName:           streamerConfig.BotName,
-		RegFind:           regexp.MustCompile(streamerConfig.ValueRegex),
+		RegFind:           regFind,
 		LineFilterContain: streamerConfig.LineFilterContain,
 		LogMessage:        streamerConfig.LogMessage,
+		NotifyThreshold:   notifyThreshold,
 		ChannelName:       channelName,
-	}
+	}, nil
 }
 
-func NewStreamersFromMap(streamers map[string]*config.StreamerConfig) map[string]*Streamer {
+// NewStreamersFromMap builds every streamer and returns all config errors at
+// once, so nothing is swapped in unless the whole file is valid.
+func NewStreamersFromMap(streamers map[string]*config.StreamerConfig) (map[string]*Streamer, error) {
 	streamersMap := make(map[string]*Streamer)
+	var errs []error
 	for k, v := range streamers {
-		streamersMap[k] = NewStreamer(*v, k)
+		if v == nil {
+			errs = append(errs, fmt.Errorf("%s: empty streamer config", k))
+			continue
+		}
+		streamer, err := NewStreamer(*v, k)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		streamersMap[k] = streamer
 	}
-	return streamersMap
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return streamersMap, nil
+}
+
+// NewStreamersFromDB builds the tracked streamers from enabled database rows.
+func NewStreamersFromDB(rows []db.Streamer) (map[string]*Streamer, error) {
+	configs := make(map[string]*config.StreamerConfig, len(rows))
+	for _, row := range rows {
+		configs[row.Channel] = streamerConfigFromDB(row)
+	}
+	return NewStreamersFromMap(configs)
+}
+
+func streamerConfigFromDB(row db.Streamer) *config.StreamerConfig {
+	return &config.StreamerConfig{
+		BotName:           row.BotName,
+		ValueRegex:        row.ValueRegex,
+		LineFilterContain: row.LineFilterContain,
+		LogMessage:        row.LogMessage,
+		NotifyThreshold:   row.NotifyThreshold,
+		Disabled:          !row.Enabled,
+	}
+}
+
+// sameConfig reports whether two streamers would behave identically.
+func (s *Streamer) sameConfig(other *Streamer) bool {
+	return s.BotName == other.BotName &&
+		s.RegFind.String() == other.RegFind.String() &&
+		s.LineFilterContain == other.LineFilterContain &&
+		s.LogMessage == other.LogMessage &&
+		s.NotifyThreshold == other.NotifyThreshold
 }
 
 func (s *Streamer) FindDonation(message string) int64 {
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// configChangedBy marks streamer rows written from the config file.
+const configChangedBy = "config"
+
+// SaveStreamer validates sc, stores it for channel and records the change in
+// the streamer history, all in one transaction.
+func (app *application) SaveStreamer(ctx context.Context, channel string, sc config.StreamerConfig, changedBy string) (db.Streamer, error) {
+	if _, err := NewStreamer(sc, channel); err != nil {
+		return db.Streamer{}, err
+	}
+	notifyThreshold := sc.NotifyThreshold
+	if notifyThreshold == 0 {
+		notifyThreshold = donationLimitNotification
+	}
+
+	var saved db.Streamer
+	err := db.RunInTx(ctx, app.database, func(q *db.Queries) error {
+		var err error
+		saved, err = q.UpsertStreamer(ctx, db.UpsertStreamerParams{
+			Channel:           channel,
+			BotName:           sc.BotName,
+			ValueRegex:        sc.ValueRegex,
+			LineFilterContain: sc.LineFilterContain,
+			LogMessage:        sc.LogMessage,
+			NotifyThreshold:   notifyThreshold,
+			Enabled:           !sc.Disabled,
+			UpdatedBy:         changedBy,
+		})
+		if err != nil {
+			return err
+		}
+		return q.CreateStreamerHistory(ctx, db.CreateStreamerHistoryParams{
+			StreamerID:        saved.ID,
+			Channel:           saved.Channel,
+			BotName:           saved.BotName,
+			ValueRegex:        saved.ValueRegex,
+			LineFilterContain: saved.LineFilterContain,
+			LogMessage:        saved.LogMessage,
+			NotifyThreshold:   saved.NotifyThreshold,
+			Enabled:           saved.Enabled,
+			ChangedBy:         changedBy,
+		})
+	})
+	if err != nil {
+		return db.Streamer{}, fmt.Errorf("failed to save streamer %s: %w", channel, err)
+	}
+	return saved, nil
+}
+
+// ImportConfigStreamers copies the streamers from the config file into the
+// database: new entries are added and the ones that differ are overwritten,
+// so editing the file still works for the channels it lists. Rows last edited
+// elsewhere, e.g. by the streamer Discord command, are never overwritten.
+// Returns the changed channels.
+func (app *application) ImportConfigStreamers(ctx context.Context, streamers map[string]*config.StreamerConfig) ([]string, error) {
+	var imported []string
+	for _, channel := range slices.Sorted(maps.Keys(streamers)) {
+		sc := streamers[channel]
+		existing, err := app.db.GetStreamerByChannel(ctx, channel)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return imported, err
+		}
+		if err == nil && (existing.UpdatedBy != configChangedBy || *streamerConfigFromDB(existing) == normalizeStreamerConfig(*sc)) {
+			continue
+		}
+		if _, err := app.SaveStreamer(ctx, channel, *sc, configChangedBy); err != nil {
+			return imported, err
+		}
+		imported = append(imported, channel)
+	}
+	return imported, nil
+}
+
+// StartStreamers merges the streamers of the config file into the database,
+// so edits made while the app was stopped are applied, and loads the enabled
+// ones as the tracked set.
+func (app *application) StartStreamers(ctx context.Context) error {
+	imported, err := app.ImportConfigStreamers(ctx, app.cfg.Streamers)
+	if err != nil {
+		return fmt.Errorf("failed to import streamers from %s: %w", app.cfg.Source, err)
+	}
+	if len(imported) > 0 {
+		app.logger.Info("imported streamers into the database", "source", app.cfg.Source, "channels", imported)
+	}
+
+	app.streamers, err = app.LoadStreamers(ctx)
+	if err != nil {
+		return fmt.Errorf("invalid streamers config: %w", err)
+	}
+	return nil
+}
+
+// LoadStreamers reads every enabled streamer from the database.
+func (app *application) LoadStreamers(ctx context.Context) (map[string]*Streamer, error) {
+	rows, err := app.db.ListEnabledStreamers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list streamers: %w", err)
+	}
+	return NewStreamersFromDB(rows)
+}
+
+func normalizeStreamerConfig(sc config.StreamerConfig) config.StreamerConfig {
+	if sc.NotifyThreshold == 0 {
+		sc.NotifyThreshold = donationLimitNotification
+	}
+	return sc
+}
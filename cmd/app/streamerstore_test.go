@@ -0,0 +1,76 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestImportConfigStreamersKeepsDiscordEdits(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	streamers := map[string]*config.StreamerConfig{
+		"#tartancz": {BotName: "bot", ValueRegex: `(\d+)`},
+		"#other":    {BotName: "bot", ValueRegex: `(\d+)`},
+	}
+	if _, err := app.ImportConfigStreamers(ctx, streamers); err != nil {
+		t.Fatal(err)
+	}
+	edited := *streamers["#tartancz"]
+	edited.Disabled = true
+	if _, err := app.SaveStreamer(ctx, "#tartancz", edited, discordChangedBy); err != nil {
+		t.Fatal(err)
+	}
+
+	streamers["#tartancz"].NotifyThreshold = 5
+	streamers["#other"].NotifyThreshold = 5
+	streamers["#new"] = &config.StreamerConfig{BotName: "bot", ValueRegex: `(\d+)`}
+	imported, err := app.ImportConfigStreamers(ctx, streamers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"#new", "#other"}; !slices.Equal(imported, want) {
+		t.Errorf("imported %v, want %v", imported, want)
+	}
+	row, err := app.db.GetStreamerByChannel(ctx, "#tartancz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Enabled || row.UpdatedBy != discordChangedBy {
+		t.Errorf("Discord edit was overwritten: enabled %v, updated by %q", row.Enabled, row.UpdatedBy)
+	}
+}
+
+func TestStartStreamersAppliesConfigEdits(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	app.cfg.Streamers = map[string]*config.StreamerConfig{
+		"#tartancz": {BotName: "bot", ValueRegex: `(\d+)`},
+		"#other":    {BotName: "bot", ValueRegex: `(\d+)`},
+	}
+	if err := app.StartStreamers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SaveStreamer(ctx, "#other", config.StreamerConfig{BotName: "edited", ValueRegex: `(\d+)`}, discordChangedBy); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is edited while the app is stopped
+	app.cfg.Streamers["#tartancz"].ValueRegex = `\d+`
+	app.cfg.Streamers["#other"].BotName = "file"
+	app.cfg.Streamers["#new"] = &config.StreamerConfig{BotName: "bot", ValueRegex: `(\d+)`}
+	if err := app.StartStreamers(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := app.GetStreamer("#tartancz").RegFind.String(); got != `\d+` {
+		t.Errorf("#tartancz regex = %q, want the edited one", got)
+	}
+	if got := app.GetStreamer("#other").BotName; got != "edited" {
+		t.Errorf("#other bot = %q, the Discord edit was overwritten", got)
+	}
+	if app.GetStreamer("#new") == nil {
+		t.Error("#new was not imported")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/export"
+	"TwitchDonoCalculator/internal/webhook"
+	"context"
+	"fmt"
+)
+
+func webhookTargets(webhooks map[string]*config.WebhookConfig) []webhook.Target {
+	targets := make([]webhook.Target, 0, len(webhooks))
+	for name, w := range webhooks {
+		targets = append(targets, webhook.Target{
+			Name:     name,
+			URL:      w.URL,
+			Secret:   w.Secret,
+			Channels: w.Channels,
+		})
+	}
+	return targets
+}
+
+// enqueueWebhooks queues a donation for every webhook of its channel, it is
+// sent in the background by the outbox.
+func (app *application) enqueueWebhooks(d db.Donation) {
+	if app.webhooks == nil {
+		return
+	}
+	if err := app.webhooks.Enqueue(context.Background(), d.Channel, webhook.EventDonation, export.NewRow(d)); err != nil {
+		app.logger.Error("failed to queue webhooks", "channel", d.Channel, "donation", d.ID, "error", err)
+	}
+}
+
+// webhookDead announces a delivery that ran out of attempts.
+func (app *application) webhookDead(d db.WebhookDelivery) {
+	app.logger.Warn("webhook delivery failed for good", "webhook", d.Webhook, "delivery", d.ID, "error", d.LastError)
+	fmt.Fprintf(app.discord.Alerts(d.Channel), "Webhook %s gave up on delivery %d after %d attempts: %s\nUse `webhook redeliver %d` to send it again.", d.Webhook, d.ID, d.Attempts, d.LastError, d.ID)
+}
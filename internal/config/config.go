@@ -5,136 +5,362 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"strconv"
 	"time"
 )
 
-const JsonFilePath = "./streamers.json"
+const (
+	// DefaultPath is where the config file is looked up when no -config flag
+	// or CONFIG_PATH is given.
+	DefaultPath = "./config.json"
+	// JsonFilePath is the legacy streamers-only file. It is still read when
+	// no config file exists so older deployments keep working.
+	JsonFilePath = "./streamers.json"
+)
 
 type Config struct {
-	Env               string
-	LogFolder         string
-	LogAll            bool
-	LogUnknownMessage bool
-	DB                DBConfig
-	Twitch            TwitchConfig
-	Streamers         map[string]*StreamerConfig
+	Env               string                     `json:"env"`
+	LogFolder         string                     `json:"logFolder"`
+	LogAll            bool                       `json:"logAll"`
+	LogUnknownMessage bool                       `json:"logUnknownMessage"`
+	Timezone          string                     `json:"timezone"`
+	DB                DBConfig                   `json:"db"`
+	Twitch            TwitchConfig               `json:"twitch"`
+	Discord           DiscordConfig              `json:"discord"`
+	Session           SessionConfig              `json:"session"`
+	Health            HealthConfig               `json:"health"`
+	Export            ExportConfig               `json:"export"`
+	HTTP              HTTPConfig                 `json:"http"`
+	Webhooks          map[string]*WebhookConfig  `json:"webhooks"`
+	Streamers         map[string]*StreamerConfig `json:"streamers"`
+
+	// Path is the path Load was called with and Source is the file the
+	// streamers were actually read from (Path or the legacy JsonFilePath).
+	Path   string `json:"-"`
+	Source string `json:"-"`
+	// Location is Timezone loaded by Load, used for reports and date ranges.
+	Location *time.Location `json:"-"`
 }
 
 type DBConfig struct {
-	DSN          string
-	MaxOpenConns int
-	MaxIdleConns int
-	MaxIdleTime  time.Duration
+	DSN          string   `json:"dsn"`
+	MaxOpenConns int      `json:"maxOpenConns"`
+	MaxIdleConns int      `json:"maxIdleConns"`
+	MaxIdleTime  Duration `json:"maxIdleTime"`
 }
 
 type TwitchConfig struct {
-	OAuth string
-	Nick  string
+	OAuth string `json:"oauth"`
+	Nick  string `json:"nick"`
+}
+
+// Values of DiscordConfig.Backend.
+const (
+	// DiscordBackendBridge talks to a relay process over TCP.
+	DiscordBackendBridge = "bridge"
+	// DiscordBackendBot talks to the Discord API directly.
+	DiscordBackendBot = "bot"
+)
+
+// Values of DiscordConfig.Protocol, the protocol spoken with the relay of the
+// bridge backend.
+const (
+	BridgeProtocolLegacy = "legacy"
+	BridgeProtocolV1     = "v1"
+)
+
+// PermissionLevels are the levels of Discord callers, lowest first, see
+// discord.Level.
+var PermissionLevels = []string{"everyone", "moderator", "admin"}
+
+// DefaultDiscordAPIURL is the Discord REST API the bot backend uses unless
+// discord.bot.apiURL points it elsewhere, e.g. to a local mock.
+const DefaultDiscordAPIURL = "https://discord.com/api/v10"
+
+type DiscordConfig struct {
+	Backend string `json:"backend"`
+	// Host and Port are the address of the relay of the bridge backend.
+	Host     string           `json:"host"`
+	Port     string           `json:"port"`
+	Protocol string           `json:"protocol"`
+	Bot      DiscordBotConfig `json:"bot"`
+	// Admins are Discord user ids that always have the admin level, e.g. to
+	// grant the first permissions.
+	Admins []string `json:"admins,omitempty"`
+	// AnonymousLevel is the level of commands that don't tell who sent
+	// them, like every command of the legacy bridge protocol.
+	AnonymousLevel string `json:"anonymousLevel"`
+}
+
+type DiscordBotConfig struct {
+	Token         string `json:"token"`
+	ApplicationID string `json:"applicationId"`
+	// PublicKey is the hex ed25519 key interactions are verified with.
+	PublicKey string `json:"publicKey"`
+	// GuildID registers the slash commands in one guild, where they show up
+	// immediately, instead of globally.
+	GuildID string `json:"guildId"`
+	APIURL  string `json:"apiURL"`
+	// AlertChannel is the Discord channel id alerts are posted to, Routes
+	// sends alerts about a Twitch channel to its own Discord channel.
+	AlertChannel string            `json:"alertChannel"`
+	Routes       map[string]string `json:"routes,omitempty"`
+	// OpsChannel is the Discord channel id health alerts are posted to,
+	// the alert channel when empty.
+	OpsChannel string `json:"opsChannel,omitempty"`
+}
+
+// Values of SessionConfig.Source.
+const (
+	// SessionSourceActivity infers stream sessions from gaps in chat.
+	SessionSourceActivity = "activity"
+	// SessionSourceHelix follows the live status from the Twitch Helix API.
+	SessionSourceHelix = "helix"
+)
+
+// DefaultHelixURL is the Twitch API the helix session source asks unless
+// session.helixURL points it elsewhere.
+const DefaultHelixURL = "https://api.twitch.tv/helix"
+
+type SessionConfig struct {
+	// Gap is how long a channel has to be quiet before its stream session
+	// is considered over.
+	Gap    Duration `json:"gap"`
+	Source string   `json:"source"`
+	// ClientID and Token authenticate the helix source, Token is an app
+	// access token of the Twitch application.
+	ClientID string `json:"clientId,omitempty"`
+	Token    string `json:"token,omitempty"`
+	HelixURL string `json:"helixURL"`
+}
+
+// HealthConfig sets when the health monitor posts to the ops channel. A zero
+// duration or threshold disables its check.
+type HealthConfig struct {
+	// TwitchDown is how long the Twitch connection may be down, or silent
+	// while connected, before it is reported.
+	TwitchDown Duration `json:"twitchDown"`
+	// Silence is how long a tracked channel may go without chat messages,
+	// it is reported once it is also twice its longest quiet period so far.
+	Silence Duration `json:"silence"`
+	// DiskPath is the directory whose free space is checked against
+	// MinFreeDiskMB.
+	DiskPath      string `json:"diskPath"`
+	MinFreeDiskMB int    `json:"minFreeDiskMB"`
+	// Cooldown is how long the same alert is not posted again, MaxPerHour
+	// bounds all alerts.
+	Cooldown   Duration `json:"cooldown"`
+	MaxPerHour int      `json:"maxPerHour"`
+}
+
+type ExportConfig struct {
+	// Dir is where Discord exports too large for a message are written,
+	// empty disables them.
+	Dir string `json:"dir"`
+}
+
+type HTTPConfig struct {
+	// Addr is the listen address of the JSON API, e.g. ":8080". Empty
+	// disables the API.
+	Addr   string `json:"addr"`
+	APIKey string `json:"apiKey"`
+	// OverlayKey only opens the overlay and the live feed, so it can be put
+	// into OBS and shown on stream without giving away the API key.
+	OverlayKey string `json:"overlayKey,omitempty"`
+	// FeedReplay is how many recent donations a live feed client gets when
+	// it connects.
+	FeedReplay int `json:"feedReplay"`
+}
+
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Secret signs every payload with HMAC-SHA256.
+	Secret string `json:"secret"`
+	// Channels limits the webhook to donations of these channels, empty
+	// means all channels.
+	Channels []string `json:"channels,omitempty"`
 }
 
 type StreamerConfig struct {
-	BotName           string
-	ValueRegex        string
-	LineFilterContain string
-	LogMessage        bool
+	BotName           string `json:"botName"`
+	ValueRegex        string `json:"valueRegex"`
+	LineFilterContain string `json:"lineFilterContain"`
+	LogMessage        bool   `json:"logMessage"`
+	// NotifyThreshold is the amount from which a donation is announced on
+	// Discord, 0 means the default.
+	NotifyThreshold int64 `json:"notifyThreshold,omitempty"`
+	Disabled        bool  `json:"disabled,omitempty"`
+}
+
+// Duration is a time.Duration written as "15m" in the config file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
 }
 
-func Load() *Config {
+func Default() *Config {
 	return &Config{
-		Env:               getEnv("ENV", "development"),
-		LogFolder:         getEnv("LOG_FOLDER", "./logs/"),
-		LogAll:            getEnvBool("LOG_ALL", true),
-		LogUnknownMessage: getEnvBool("LOG_UNKNOWN_MESSAGE", true),
+		Env:               "development",
+		LogFolder:         "./logs/",
+		LogAll:            true,
+		LogUnknownMessage: true,
+		Timezone:          "UTC",
 		DB: DBConfig{
-			DSN:          getEnv("DB_DSN", "db.db"),
-			MaxOpenConns: getEnvInt("DB_MAX_OPEN_CONNS", 50),
-			MaxIdleConns: getEnvInt("DB_MAX_IDLE_CONNS", 50),
-			MaxIdleTime:  getEnvDuration("DB_MAX_IDLE_TIME", time.Minute*15),
+			DSN:          "db.db",
+			MaxOpenConns: 50,
+			MaxIdleConns: 50,
+			MaxIdleTime:  Duration(time.Minute * 15),
 		},
-		Twitch: TwitchConfig{
-			OAuth: getEnv("TWITCH_OAUTH", ""),
-			Nick:  getEnv("TWITCH_NICK", ""),
+		Discord: DiscordConfig{
+			Backend:        DiscordBackendBridge,
+			Protocol:       BridgeProtocolLegacy,
+			AnonymousLevel: "everyone",
+			Bot: DiscordBotConfig{
+				APIURL: DefaultDiscordAPIURL,
+			},
 		},
-		Streamers: GetStreamersConfig(),
+		Session: SessionConfig{
+			Gap:      Duration(time.Minute * 30),
+			Source:   SessionSourceActivity,
+			HelixURL: DefaultHelixURL,
+		},
+		Health: HealthConfig{
+			TwitchDown:    Duration(time.Minute * 10),
+			Silence:       Duration(time.Hour * 12),
+			DiskPath:      ".",
+			MinFreeDiskMB: 500,
+			Cooldown:      Duration(time.Minute * 30),
+			MaxPerHour:    20,
+		},
+		Export: ExportConfig{
+			Dir: "./exports/",
+		},
+		HTTP: HTTPConfig{
+			FeedReplay: 20,
+		},
+		Webhooks:  make(map[string]*WebhookConfig),
+		Streamers: make(map[string]*StreamerConfig),
+		Location:  time.UTC,
 	}
 }
 
-func GetStreamersConfig() map[string]*StreamerConfig {
-	file, err := os.Open(JsonFilePath)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			streamers := GetNewStreamerConfig()
-			CreateStreamersFile(streamers)
+// Load builds the config from defaults, the config file at path (or the
+// legacy streamers.json when path is the default and does not exist) and the
+// environment, in that order. It never prompts; every problem found is
+// returned at once as a *validator.Validator.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	cfg.Path = path
+
+	switch _, err := os.Stat(path); {
+	case err == nil:
+		if err := readFile(path, cfg); err != nil {
+			return nil, err
+		}
+		cfg.Source = path
+	case errors.Is(err, os.ErrNotExist) && path == DefaultPath:
+		streamers, err := LoadStreamersFile(JsonFilePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
 		}
+		if err == nil {
+			cfg.Streamers = streamers
+			cfg.Source = JsonFilePath
+		}
+	default:
+		return nil, fmt.Errorf("failed to open config file: %w", err)
 	}
-	defer file.Close()
-	var streamers map[string]*StreamerConfig
-	err = json.NewDecoder(file).Decode(&streamers)
-	if err != nil {
-		panic(err)
+
+	v := applyEnv(cfg)
+	cfg.validate(v)
+	if !v.Valid() {
+		return nil, v
 	}
-	return streamers
+	cfg.Location, _ = time.LoadLocation(cfg.Timezone)
+	return cfg, nil
 }
 
-func GetNewStreamerConfig() map[string]*StreamerConfig {
-	streamerConfig := &StreamerConfig{}
-	fmt.Print("Enter bot name to watch in Twitch (e.g. streamelements): ")
-	fmt.Scanln(&streamerConfig.BotName)
-	fmt.Print("Enter channel name to watch: ")
-	var channelName string
-	fmt.Scanln(&channelName)
-	fmt.Print("Enter string chat message should contain: ")
-	fmt.Scanln(&streamerConfig.LineFilterContain)
-	fmt.Print("Enter Regex for float number: ")
-	fmt.Scanln(&streamerConfig.ValueRegex)
+func readFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
 
-	streamerConfigs := make(map[string]*StreamerConfig)
-	streamerConfigs[channelName] = streamerConfig
-	return streamerConfigs
+// LoadStreamersFile reads and decodes the legacy streamers file at path.
+func LoadStreamersFile(path string) (map[string]*StreamerConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var streamers map[string]*StreamerConfig
+	if err := json.NewDecoder(file).Decode(&streamers); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return streamers, nil
 }
 
-func CreateStreamersFile(s map[string]*StreamerConfig) error {
-	file, err := os.Create(JsonFilePath)
+// WriteFile writes cfg to path as indented JSON.
+func WriteFile(path string, cfg *Config) error {
+	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 	enc := json.NewEncoder(file)
 	enc.SetIndent("", "\t")
-	return enc.Encode(s)
+	return enc.Encode(cfg)
 }
 
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultValue
-}
+const redacted = "REDACTED"
 
-func getEnvInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
+// Redacted returns a copy of c with every secret replaced, safe to print.
+func (c *Config) Redacted() *Config {
+	clone := *c
+	if clone.Twitch.OAuth != "" {
+		clone.Twitch.OAuth = redacted
 	}
-	return defaultValue
-}
-
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value, exists := os.LookupEnv(key); exists {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
-		}
+	if clone.Discord.Bot.Token != "" {
+		clone.Discord.Bot.Token = redacted
 	}
-	return defaultValue
-}
-
-func getEnvBool(key string, defaultValue bool) bool {
-	if value, exists := os.LookupEnv(key); exists {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
+	if clone.Session.Token != "" {
+		clone.Session.Token = redacted
+	}
+	if clone.HTTP.APIKey != "" {
+		clone.HTTP.APIKey = redacted
+	}
+	if clone.HTTP.OverlayKey != "" {
+		clone.HTTP.OverlayKey = redacted
+	}
+	clone.Webhooks = make(map[string]*WebhookConfig, len(c.Webhooks))
+	for name, w := range c.Webhooks {
+		if w != nil {
+			redactedWebhook := *w
+			redactedWebhook.Secret = redacted
+			w = &redactedWebhook
 		}
+		clone.Webhooks[name] = w
 	}
-	return defaultValue
+	return &clone
 }
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"TwitchDonoCalculator/internal/validator"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv overrides cfg with every environment variable that is set. Values
+// that cannot be parsed are reported instead of silently falling back.
+//
+//	ENV, LOG_FOLDER, LOG_ALL, LOG_UNKNOWN_MESSAGE, TIMEZONE,
+//	DB_DSN, DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_MAX_IDLE_TIME,
+//	TWITCH_OAUTH, TWITCH_NICK,
+//	DISCORD_BACKEND, DISCORD_BOT_SERVER_HOST, DISCORD_BOT_SERVER_PORT,
+//	DISCORD_BRIDGE_PROTOCOL,
+//	DISCORD_BOT_TOKEN, DISCORD_APPLICATION_ID, DISCORD_PUBLIC_KEY,
+//	DISCORD_GUILD_ID, DISCORD_API_URL, DISCORD_ALERT_CHANNEL,
+//	DISCORD_OPS_CHANNEL,
+//	DISCORD_ADMINS (comma separated), DISCORD_ANONYMOUS_LEVEL,
+//	SESSION_GAP, SESSION_SOURCE, SESSION_HELIX_URL,
+//	TWITCH_CLIENT_ID, TWITCH_APP_TOKEN,
+//	HEALTH_TWITCH_DOWN, HEALTH_SILENCE, HEALTH_DISK_PATH,
+//	HEALTH_MIN_FREE_DISK_MB, HEALTH_COOLDOWN, HEALTH_MAX_PER_HOUR,
+//	EXPORT_DIR, HTTP_ADDR, HTTP_API_KEY, HTTP_OVERLAY_KEY, HTTP_FEED_REPLAY
+func applyEnv(cfg *Config) *validator.Validator {
+	v := &validator.Validator{}
+
+	envString("ENV", &cfg.Env)
+	envString("LOG_FOLDER", &cfg.LogFolder)
+	envBool(v, "LOG_ALL", &cfg.LogAll)
+	envBool(v, "LOG_UNKNOWN_MESSAGE", &cfg.LogUnknownMessage)
+	envString("TIMEZONE", &cfg.Timezone)
+
+	envString("DB_DSN", &cfg.DB.DSN)
+	envInt(v, "DB_MAX_OPEN_CONNS", &cfg.DB.MaxOpenConns)
+	envInt(v, "DB_MAX_IDLE_CONNS", &cfg.DB.MaxIdleConns)
+	envDuration(v, "DB_MAX_IDLE_TIME", &cfg.DB.MaxIdleTime)
+
+	envString("TWITCH_OAUTH", &cfg.Twitch.OAuth)
+	envString("TWITCH_NICK", &cfg.Twitch.Nick)
+
+	envString("DISCORD_BOT_SERVER_HOST", &cfg.Discord.Host)
+	envString("DISCORD_BOT_SERVER_PORT", &cfg.Discord.Port)
+	envString("DISCORD_BRIDGE_PROTOCOL", &cfg.Discord.Protocol)
+	envString("DISCORD_BACKEND", &cfg.Discord.Backend)
+	envString("DISCORD_BOT_TOKEN", &cfg.Discord.Bot.Token)
+	envString("DISCORD_APPLICATION_ID", &cfg.Discord.Bot.ApplicationID)
+	envString("DISCORD_PUBLIC_KEY", &cfg.Discord.Bot.PublicKey)
+	envString("DISCORD_GUILD_ID", &cfg.Discord.Bot.GuildID)
+	envString("DISCORD_API_URL", &cfg.Discord.Bot.APIURL)
+	envString("DISCORD_ALERT_CHANNEL", &cfg.Discord.Bot.AlertChannel)
+	envString("DISCORD_OPS_CHANNEL", &cfg.Discord.Bot.OpsChannel)
+	envList("DISCORD_ADMINS", &cfg.Discord.Admins)
+	envString("DISCORD_ANONYMOUS_LEVEL", &cfg.Discord.AnonymousLevel)
+
+	envDuration(v, "SESSION_GAP", &cfg.Session.Gap)
+	envString("SESSION_SOURCE", &cfg.Session.Source)
+	envString("SESSION_HELIX_URL", &cfg.Session.HelixURL)
+	envString("TWITCH_CLIENT_ID", &cfg.Session.ClientID)
+	envString("TWITCH_APP_TOKEN", &cfg.Session.Token)
+
+	envDuration(v, "HEALTH_TWITCH_DOWN", &cfg.Health.TwitchDown)
+	envDuration(v, "HEALTH_SILENCE", &cfg.Health.Silence)
+	envString("HEALTH_DISK_PATH", &cfg.Health.DiskPath)
+	envInt(v, "HEALTH_MIN_FREE_DISK_MB", &cfg.Health.MinFreeDiskMB)
+	envDuration(v, "HEALTH_COOLDOWN", &cfg.Health.Cooldown)
+	envInt(v, "HEALTH_MAX_PER_HOUR", &cfg.Health.MaxPerHour)
+
+	envString("EXPORT_DIR", &cfg.Export.Dir)
+
+	envString("HTTP_ADDR", &cfg.HTTP.Addr)
+	envString("HTTP_API_KEY", &cfg.HTTP.APIKey)
+	envString("HTTP_OVERLAY_KEY", &cfg.HTTP.OverlayKey)
+	envInt(v, "HTTP_FEED_REPLAY", &cfg.HTTP.FeedReplay)
+
+	return v
+}
+
+func envString(key string, target *string) {
+	if value, exists := os.LookupEnv(key); exists {
+		*target = value
+	}
+}
+
+// envList splits a comma separated value, an empty value clears target.
+func envList(key string, target *[]string) {
+	if value, exists := os.LookupEnv(key); exists {
+		*target = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*target = append(*target, item)
+			}
+		}
+	}
+}
+
+func envInt(v *validator.Validator, key string, target *int) {
+	if value, exists := os.LookupEnv(key); exists {
+		intValue, err := strconv.Atoi(value)
+		v.CheckField(err == nil, key, "must be an integer")
+		if err == nil {
+			*target = intValue
+		}
+	}
+}
+
+func envDuration(v *validator.Validator, key string, target *Duration) {
+	if value, exists := os.LookupEnv(key); exists {
+		duration, err := time.ParseDuration(value)
+		v.CheckField(err == nil, key, "must be a duration like 15m")
+		if err == nil {
+			*target = Duration(duration)
+		}
+	}
+}
+
+func envBool(v *validator.Validator, key string, target *bool) {
+	if value, exists := os.LookupEnv(key); exists {
+		boolValue, err := strconv.ParseBool(value)
+		v.CheckField(err == nil, key, "must be true or false")
+		if err == nil {
+			*target = boolValue
+		}
+	}
+}
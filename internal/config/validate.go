@@ -0,0 +1,166 @@
+package config
+
+import (
+	"TwitchDonoCalculator/internal/validator"
+	"crypto/ed25519"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks the whole config and returns every problem found as a
+// *validator.Validator, or nil when the config is usable.
+func (c *Config) Validate() error {
+	v := &validator.Validator{}
+	c.validate(v)
+	if !v.Valid() {
+		return v
+	}
+	return nil
+}
+
+func (c *Config) validate(v *validator.Validator) {
+	v.CheckField(c.Env != "", "env", "must not be empty")
+	v.CheckField(c.LogFolder != "", "logFolder", "must not be empty")
+	if _, err := time.LoadLocation(c.Timezone); err != nil || c.Timezone == "" {
+		v.AddFieldError("timezone", "must be an IANA timezone like Europe/Prague")
+	}
+
+	v.CheckField(c.DB.DSN != "", "db.dsn", "must not be empty")
+	v.CheckField(c.DB.MaxOpenConns >= 0, "db.maxOpenConns", "must not be negative")
+	v.CheckField(c.DB.MaxIdleConns >= 0, "db.maxIdleConns", "must not be negative")
+	v.CheckField(c.DB.MaxIdleTime >= 0, "db.maxIdleTime", "must not be negative")
+
+	switch c.Discord.Backend {
+	case DiscordBackendBridge:
+		v.CheckField(c.Discord.Protocol == BridgeProtocolLegacy || c.Discord.Protocol == BridgeProtocolV1, "discord.protocol", "must be legacy or v1")
+	case DiscordBackendBot:
+		c.validateDiscordBot(v)
+	default:
+		v.AddFieldError("discord.backend", "must be bridge or bot")
+	}
+	for _, id := range c.Discord.Admins {
+		v.CheckField(isSnowflake(id), "discord.admins", "must be Discord user ids")
+	}
+	v.CheckField(slices.Contains(PermissionLevels, c.Discord.AnonymousLevel), "discord.anonymousLevel", "must be everyone, moderator or admin")
+
+	v.CheckField(c.Session.Gap > 0, "session.gap", "must be positive")
+	switch c.Session.Source {
+	case SessionSourceActivity:
+	case SessionSourceHelix:
+		v.CheckField(c.Session.ClientID != "", "session.clientId", "must not be empty for the helix source")
+		v.CheckField(c.Session.Token != "", "session.token", "must not be empty for the helix source")
+		u, err := url.Parse(c.Session.HelixURL)
+		v.CheckField(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "session.helixURL", "must be an http or https URL")
+	default:
+		v.AddFieldError("session.source", "must be activity or helix")
+	}
+
+	v.CheckField(c.Health.TwitchDown >= 0, "health.twitchDown", "must not be negative")
+	v.CheckField(c.Health.Silence >= 0, "health.silence", "must not be negative")
+	v.CheckField(c.Health.MinFreeDiskMB >= 0, "health.minFreeDiskMB", "must not be negative")
+	v.CheckField(c.Health.MinFreeDiskMB == 0 || c.Health.DiskPath != "", "health.diskPath", "must not be empty when health.minFreeDiskMB is set")
+	v.CheckField(c.Health.Cooldown >= 0, "health.cooldown", "must not be negative")
+	v.CheckField(c.Health.MaxPerHour > 0, "health.maxPerHour", "must be positive")
+
+	if c.HTTP.Addr != "" {
+		v.CheckField(len(c.HTTP.APIKey) >= 16, "http.apiKey", "must be at least 16 characters when http.addr is set")
+	}
+	if c.HTTP.OverlayKey != "" {
+		v.CheckField(len(c.HTTP.OverlayKey) >= 16, "http.overlayKey", "must be at least 16 characters")
+		v.CheckField(c.HTTP.OverlayKey != c.HTTP.APIKey, "http.overlayKey", "must differ from http.apiKey")
+	}
+	v.CheckField(c.HTTP.FeedReplay >= 0 && c.HTTP.FeedReplay <= 1000, "http.feedReplay", "must be between 0 and 1000")
+
+	for name, w := range c.Webhooks {
+		validateWebhook(v, name, w)
+	}
+
+	for channel, s := range c.Streamers {
+		ValidateStreamer(v, channel, s)
+	}
+}
+
+// ValidateStreamer checks a single streamer entry, reporting errors under
+// keys prefixed with "streamers.<channel>".
+func ValidateStreamer(v *validator.Validator, channel string, s *StreamerConfig) {
+	key := "streamers." + channel
+	// the validator keeps one message per key, so every problem goes into it
+	var problems []string
+	name, hasPrefix := strings.CutPrefix(channel, "#")
+	if !hasPrefix {
+		problems = append(problems, "channel must start with # (e.g. #tartancz)")
+	}
+	if !ValidChannel("#" + name) {
+		problems = append(problems, "channel must be a Twitch login, at most 25 lowercase letters, digits and underscores")
+	}
+	if s == nil {
+		problems = append(problems, "must not be empty")
+	}
+	if len(problems) > 0 {
+		v.AddFieldError(key, strings.Join(problems, "; "))
+	}
+	if s == nil {
+		return
+	}
+	v.CheckField(s.BotName != "", key+".botName", "must not be empty")
+	v.CheckField(s.NotifyThreshold >= 0, key+".notifyThreshold", "must not be negative")
+	if s.ValueRegex == "" {
+		v.AddFieldError(key+".valueRegex", "must not be empty")
+	} else if _, err := regexp.Compile(s.ValueRegex); err != nil {
+		v.AddFieldError(key+".valueRegex", err.Error())
+	}
+}
+
+func validateWebhook(v *validator.Validator, name string, w *WebhookConfig) {
+	key := "webhooks." + name
+	v.CheckField(name != "" && name == strings.ToLower(name) && !strings.ContainsAny(name, " \t"), key, "name must be lowercase without spaces")
+	if w == nil {
+		v.AddFieldError(key, "must not be empty")
+		return
+	}
+	u, err := url.Parse(w.URL)
+	v.CheckField(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", key+".url", "must be an http or https URL")
+	v.CheckField(len(w.Secret) >= 16, key+".secret", "must be at least 16 characters")
+	for _, channel := range w.Channels {
+		v.CheckField(ValidChannel(channel), key+".channels", "channels must be lowercase Twitch logins starting with #")
+	}
+}
+
+func (c *Config) validateDiscordBot(v *validator.Validator) {
+	bot := c.Discord.Bot
+	v.CheckField(bot.Token != "", "discord.bot.token", "must not be empty")
+	v.CheckField(isSnowflake(bot.ApplicationID), "discord.bot.applicationId", "must be a Discord id")
+	key, err := hex.DecodeString(bot.PublicKey)
+	v.CheckField(err == nil && len(key) == ed25519.PublicKeySize, "discord.bot.publicKey", "must be the hex public key of the application")
+	v.CheckField(bot.GuildID == "" || isSnowflake(bot.GuildID), "discord.bot.guildId", "must be a Discord id")
+	u, err := url.Parse(bot.APIURL)
+	v.CheckField(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "discord.bot.apiURL", "must be an http or https URL")
+	v.CheckField(bot.AlertChannel == "" || isSnowflake(bot.AlertChannel), "discord.bot.alertChannel", "must be a Discord channel id")
+	v.CheckField(bot.OpsChannel == "" || isSnowflake(bot.OpsChannel), "discord.bot.opsChannel", "must be a Discord channel id")
+	for channel, id := range bot.Routes {
+		v.CheckField(strings.HasPrefix(channel, "#") && isSnowflake(id), "discord.bot.routes."+channel, "must map a #channel to a Discord channel id")
+	}
+	// Discord delivers slash commands to the interactions endpoint of the API
+	v.CheckField(c.HTTP.Addr != "", "http.addr", "must be set for the bot backend to receive slash commands")
+}
+
+// channelRX matches a channel as it is tracked, Twitch logins are at most 25
+// letters, digits and underscores.
+var channelRX = regexp.MustCompile(`^#[a-z0-9_]{1,25}$`)
+
+// ValidChannel reports whether channel is a lowercase Twitch login starting
+// with #. Channels are written into IRC commands and used as log file names,
+// so anything else is rejected.
+func ValidChannel(channel string) bool {
+	return channelRX.MatchString(channel)
+}
+
+func isSnowflake(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
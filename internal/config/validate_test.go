@@ -0,0 +1,102 @@
+package config
+
+import (
+	"TwitchDonoCalculator/internal/validator"
+	"strings"
+	"testing"
+)
+
+func TestValidateStreamerChannel(t *testing.T) {
+	streamer := &StreamerConfig{BotName: "bot", ValueRegex: `\d+`}
+	tests := []struct {
+		channel string
+		want    []string
+	}{
+		{"#tartancz", nil},
+		{"#a_b_9", nil},
+		{"tartancz", []string{"must start with #"}},
+		{"#Foo Bar", []string{"Twitch login"}},
+		{"Foo Bar", []string{"must start with #", "Twitch login"}},
+		{"#", []string{"Twitch login"}},
+		{"#x\nPART #y", []string{"Twitch login"}},
+		{"#x\r", []string{"Twitch login"}},
+		{"#../../tmp/x", []string{"Twitch login"}},
+		{"#a/b", []string{"Twitch login"}},
+		{"#abcdefghijklmnopqrstuvwxyz0", []string{"Twitch login"}},
+	}
+	for _, tt := range tests {
+		v := &validator.Validator{}
+		ValidateStreamer(v, tt.channel, streamer)
+		got := v.FieldErrors["streamers."+tt.channel]
+		if len(tt.want) == 0 && !v.Valid() {
+			t.Errorf("%q: unexpected errors %v", tt.channel, v.FieldErrors)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%q: error %q does not say %q", tt.channel, got, want)
+			}
+		}
+	}
+}
+
+func TestValidateStreamerEmptyEntry(t *testing.T) {
+	v := &validator.Validator{}
+	ValidateStreamer(v, "Foo", nil)
+	got := v.FieldErrors["streamers.Foo"]
+	for _, want := range []string{"must start with #", "Twitch login", "must not be empty"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("error %q does not say %q", got, want)
+		}
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Session.Gap = 0
+	cfg.Health.MaxPerHour = 0
+	cfg.Streamers["Foo Bar"] = &StreamerConfig{ValueRegex: "("}
+	err := cfg.Validate()
+	v, ok := err.(*validator.Validator)
+	if !ok {
+		t.Fatalf("Validate() = %v, want a *validator.Validator", err)
+	}
+	for _, key := range []string{"session.gap", "health.maxPerHour", "streamers.Foo Bar", "streamers.Foo Bar.botName", "streamers.Foo Bar.valueRegex"} {
+		if _, ok := v.FieldErrors[key]; !ok {
+			t.Errorf("no error for %s in %v", key, v.FieldErrors)
+		}
+	}
+}
+
+func TestValidateOverlayKey(t *testing.T) {
+	tests := []struct {
+		overlayKey string
+		want       string
+	}{
+		{"", ""},
+		{"overlay-0123456789", ""},
+		{"short", "at least 16 characters"},
+		{"0123456789abcdef", "must differ from http.apiKey"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.HTTP.Addr = ":8080"
+		cfg.HTTP.APIKey = "0123456789abcdef"
+		cfg.HTTP.OverlayKey = tt.overlayKey
+		err := cfg.Validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%q: %v", tt.overlayKey, err)
+			}
+			continue
+		}
+		v, ok := err.(*validator.Validator)
+		if !ok || !strings.Contains(v.FieldErrors["http.overlayKey"], tt.want) {
+			t.Errorf("%q: error %v, want %q", tt.overlayKey, err, tt.want)
+		}
+	}
+	cfg := Default()
+	cfg.HTTP.OverlayKey = "overlay-0123456789"
+	if got := cfg.Redacted().HTTP.OverlayKey; got != redacted {
+		t.Errorf("redacted overlay key %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// debounceDelay groups the burst of events editors produce on save
+// (truncate, write, chmod, rename) into a single change notification.
+const debounceDelay = 500 * time.Millisecond
+
+// WatchFile calls onChange every time the file at path is modified until ctx
+// is cancelled. It uses fsnotify on the parent directory, so files replaced by
+// rename are still picked up, and falls back to polling the modification time
+// every pollInterval when fsnotify is not available.
+func WatchFile(ctx context.Context, path string, pollInterval time.Duration, onChange func()) {
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(path))
+	}
+	if err != nil {
+		slog.Warn("fsnotify unavailable, polling the file instead", "path", path, "interval", pollInterval, "error", err)
+		if watcher != nil {
+			watcher.Close()
+		}
+		pollFile(ctx, path, pollInterval, onChange)
+		return
+	}
+	defer watcher.Close()
+
+	target := filepath.Clean(path)
+	debounce := time.NewTimer(debounceDelay)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != target {
+				continue
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+				debounce.Reset(debounceDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Error("error while watching the file", "path", path, "error", err)
+		case <-debounce.C:
+			onChange()
+		}
+	}
+}
+
+func pollFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(path); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+				continue
+			}
+			lastMod, lastSize = info.ModTime(), info.Size()
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PromptStreamerConfig asks for a single streamer on out and reads the answers
+// line by line from in. It is only used by the init command.
+func PromptStreamerConfig(in io.Reader, out io.Writer) (string, *StreamerConfig, error) {
+	scanner := bufio.NewScanner(in)
+	ask := func(question string) (string, error) {
+		fmt.Fprint(out, question)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", errors.New("unexpected end of input")
+		}
+		return strings.TrimSpace(scanner.Text()), nil
+	}
+
+	streamerConfig := &StreamerConfig{}
+	var err error
+	if streamerConfig.BotName, err = ask("Enter bot name to watch in Twitch (e.g. streamelements): "); err != nil {
+		return "", nil, err
+	}
+	channelName, err := ask("Enter channel name to watch: ")
+	if err != nil {
+		return "", nil, err
+	}
+	if streamerConfig.LineFilterContain, err = ask("Enter string chat message should contain: "); err != nil {
+		return "", nil, err
+	}
+	if streamerConfig.ValueRegex, err = ask("Enter Regex for float number: "); err != nil {
+		return "", nil, err
+	}
+
+	channelName = strings.ToLower(channelName)
+	if !strings.HasPrefix(channelName, "#") {
+		channelName = "#" + channelName
+	}
+	return channelName, streamerConfig, nil
+}
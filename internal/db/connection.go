@@ -17,7 +17,7 @@ func OpenDB(cfg config.DBConfig) (*sql.DB, error) {
 
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
-	db.SetConnMaxIdleTime(cfg.MaxIdleTime)
+	db.SetConnMaxIdleTime(time.Duration(cfg.MaxIdleTime))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
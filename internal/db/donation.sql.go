@@ -7,21 +7,50 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
+const countDuplicateDonations = `-- name: CountDuplicateDonations :one
+SELECT COUNT(*) FROM donation d
+WHERE d.channel = ?1
+  AND lower(d.send_from) = lower(?2)
+  AND d.amount = ?3
+  AND datetime(d."timestamp") = datetime(?4)
+`
+
+type CountDuplicateDonationsParams struct {
+	Channel   string
+	SendFrom  string
+	Amount    int64
+	DonatedAt time.Time
+}
+
+func (q *Queries) CountDuplicateDonations(ctx context.Context, arg CountDuplicateDonationsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countDuplicateDonations,
+		arg.Channel,
+		arg.SendFrom,
+		arg.Amount,
+		arg.DonatedAt,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createDonation = `-- name: CreateDonation :one
-insert into donation(user, channel, send_from, amount, text)    
-VALUES(?, ?, ?, ?, ?)
-RETURNING id, user, channel, send_from, amount, text, timestamp
+insert into donation(user, channel, send_from, amount, text, session_id)    
+VALUES(?, ?, ?, ?, ?, ?)
+RETURNING id, user, channel, send_from, amount, text, timestamp, donor_id, session_id, source
 `
 
 type CreateDonationParams struct {
-	User     string
-	Channel  string
-	SendFrom string
-	Amount   int64
-	Text     string
+	User      string
+	Channel   string
+	SendFrom  string
+	Amount    int64
+	Text      string
+	SessionID sql.NullInt64
 }
 
 func (q *Queries) CreateDonation(ctx context.Context, arg CreateDonationParams) (Donation, error) {
@@ -31,6 +60,7 @@ func (q *Queries) CreateDonation(ctx context.Context, arg CreateDonationParams)
 		arg.SendFrom,
 		arg.Amount,
 		arg.Text,
+		arg.SessionID,
 	)
 	var i Donation
 	err := row.Scan(
@@ -41,6 +71,9 @@ func (q *Queries) CreateDonation(ctx context.Context, arg CreateDonationParams)
 		&i.Amount,
 		&i.Text,
 		&i.Timestamp,
+		&i.DonorID,
+		&i.SessionID,
+		&i.Source,
 	)
 	return i, err
 }
@@ -48,11 +81,11 @@ func (q *Queries) CreateDonation(ctx context.Context, arg CreateDonationParams)
 const getSumDonationByStreamer = `-- name: GetSumDonationByStreamer :many
 SELECT
     CAST(COALESCE(SUM(d.amount), 0) AS INTEGER) AS amount,
-    CAST(strftime('%Y-%m-%d', MIN(d."timestamp")) AS TEXT)  AS StartingDate,
-    CAST(strftime('%Y-%m-%d', MAX(d."timestamp")) AS TEXT)  AS EndingDate,
+    CAST(datetime(MIN(d."timestamp")) AS TEXT)  AS StartingDate,
+    CAST(datetime(MAX(d."timestamp")) AS TEXT)  AS EndingDate,
     d.channel
 FROM donation d 
-WHERE d."timestamp" BETWEEN ? AND ?
+WHERE datetime(d."timestamp") >= datetime(?1) AND datetime(d."timestamp") < datetime(?2)
 GROUP BY d.channel
 `
 
@@ -95,3 +128,278 @@ func (q *Queries) GetSumDonationByStreamer(ctx context.Context, arg GetSumDonati
 	}
 	return items, nil
 }
+
+const importDonation = `-- name: ImportDonation :exec
+INSERT INTO donation(user, channel, send_from, amount, text, "timestamp", source)
+VALUES(?, ?, ?, ?, ?, ?, ?)
+`
+
+type ImportDonationParams struct {
+	User      string
+	Channel   string
+	SendFrom  string
+	Amount    int64
+	Text      string
+	Timestamp time.Time
+	Source    string
+}
+
+func (q *Queries) ImportDonation(ctx context.Context, arg ImportDonationParams) error {
+	_, err := q.db.ExecContext(ctx, importDonation,
+		arg.User,
+		arg.Channel,
+		arg.SendFrom,
+		arg.Amount,
+		arg.Text,
+		arg.Timestamp,
+		arg.Source,
+	)
+	return err
+}
+
+const listDonationAmounts = `-- name: ListDonationAmounts :many
+SELECT d.channel, d.amount, d."timestamp"
+FROM donation d
+WHERE (CAST(?1 AS TEXT) = '' OR d.channel = ?1)
+  AND datetime(d."timestamp") >= datetime(?2) AND datetime(d."timestamp") < datetime(?3)
+ORDER BY d.channel, datetime(d."timestamp")
+`
+
+type ListDonationAmountsParams struct {
+	Channel       string
+	FromTimestamp time.Time
+	ToTimestamp   time.Time
+}
+
+type ListDonationAmountsRow struct {
+	Channel   string
+	Amount    int64
+	Timestamp time.Time
+}
+
+func (q *Queries) ListDonationAmounts(ctx context.Context, arg ListDonationAmountsParams) ([]ListDonationAmountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDonationAmounts, arg.Channel, arg.FromTimestamp, arg.ToTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDonationAmountsRow
+	for rows.Next() {
+		var i ListDonationAmountsRow
+		if err := rows.Scan(&i.Channel, &i.Amount, &i.Timestamp); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDonationsForExport = `-- name: ListDonationsForExport :many
+SELECT id, user, channel, send_from, amount, text, timestamp, donor_id, session_id, source FROM donation d
+WHERE (CAST(?1 AS TEXT) = '' OR d.channel = ?1)
+  AND (CAST(?2 AS TEXT) = '' OR lower(d.send_from) = lower(?2))
+  AND datetime(d."timestamp") >= datetime(?3) AND datetime(d."timestamp") < datetime(?4)
+  AND d.id > ?5
+ORDER BY d.id
+LIMIT ?6
+`
+
+type ListDonationsForExportParams struct {
+	Channel       string
+	Donor         string
+	FromTimestamp time.Time
+	ToTimestamp   time.Time
+	AfterID       int64
+	RowLimit      int64
+}
+
+func (q *Queries) ListDonationsForExport(ctx context.Context, arg ListDonationsForExportParams) ([]Donation, error) {
+	rows, err := q.db.QueryContext(ctx, listDonationsForExport,
+		arg.Channel,
+		arg.Donor,
+		arg.FromTimestamp,
+		arg.ToTimestamp,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Donation
+	for rows.Next() {
+		var i Donation
+		if err := rows.Scan(
+			&i.ID,
+			&i.User,
+			&i.Channel,
+			&i.SendFrom,
+			&i.Amount,
+			&i.Text,
+			&i.Timestamp,
+			&i.DonorID,
+			&i.SessionID,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLastDonations = `-- name: ListLastDonations :many
+SELECT id, user, channel, send_from, amount, text, timestamp, donor_id, session_id, source FROM donation d
+WHERE (CAST(?1 AS TEXT) = '' OR d.channel = ?1)
+  AND (CAST(?2 AS TEXT) = '' OR lower(d.send_from) = lower(?2))
+  AND d.amount >= ?3
+  AND datetime(d."timestamp") >= datetime(?4)
+  AND datetime(d."timestamp") < datetime(?5)
+  AND (
+    CAST(?6 AS INTEGER) = 0
+    OR datetime(d."timestamp") < datetime(?7)
+    OR (datetime(d."timestamp") = datetime(?7) AND d.id < ?6)
+  )
+ORDER BY datetime(d."timestamp") DESC, d.id DESC
+LIMIT ?8
+`
+
+type ListLastDonationsParams struct {
+	Channel         string
+	Donor           string
+	MinAmount       int64
+	Since           time.Time
+	Until           time.Time
+	CursorID        int64
+	CursorTimestamp time.Time
+	RowLimit        int64
+}
+
+func (q *Queries) ListLastDonations(ctx context.Context, arg ListLastDonationsParams) ([]Donation, error) {
+	rows, err := q.db.QueryContext(ctx, listLastDonations,
+		arg.Channel,
+		arg.Donor,
+		arg.MinAmount,
+		arg.Since,
+		arg.Until,
+		arg.CursorID,
+		arg.CursorTimestamp,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Donation
+	for rows.Next() {
+		var i Donation
+		if err := rows.Scan(
+			&i.ID,
+			&i.User,
+			&i.Channel,
+			&i.SendFrom,
+			&i.Amount,
+			&i.Text,
+			&i.Timestamp,
+			&i.DonorID,
+			&i.SessionID,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTopDonors = `-- name: ListTopDonors :many
+SELECT
+    CAST(COALESCE(NULLIF(d.donor_id, ''), lower(d.send_from)) AS TEXT) AS donor_key,
+    CAST(MAX(d.send_from) AS TEXT) AS donor,
+    CAST(COALESCE(SUM(d.amount), 0) AS INTEGER) AS total,
+    COUNT(*) AS donations,
+    CAST(COALESCE(MAX(d.amount), 0) AS INTEGER) AS largest
+FROM donation d
+WHERE (CAST(?1 AS TEXT) = '' OR d.channel = ?1)
+  AND datetime(d."timestamp") >= datetime(?2) AND datetime(d."timestamp") < datetime(?3)
+GROUP BY donor_key
+ORDER BY
+    CASE CAST(?4 AS TEXT)
+        WHEN 'count' THEN COUNT(*)
+        WHEN 'largest' THEN MAX(d.amount)
+        ELSE SUM(d.amount)
+    END DESC,
+    SUM(d.amount) DESC,
+    COUNT(*) DESC,
+    donor_key ASC
+LIMIT ?5
+`
+
+type ListTopDonorsParams struct {
+	Channel       string
+	FromTimestamp time.Time
+	ToTimestamp   time.Time
+	OrderBy       string
+	RowLimit      int64
+}
+
+type ListTopDonorsRow struct {
+	DonorKey  string
+	Donor     string
+	Total     int64
+	Donations int64
+	Largest   int64
+}
+
+func (q *Queries) ListTopDonors(ctx context.Context, arg ListTopDonorsParams) ([]ListTopDonorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTopDonors,
+		arg.Channel,
+		arg.FromTimestamp,
+		arg.ToTimestamp,
+		arg.OrderBy,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTopDonorsRow
+	for rows.Next() {
+		var i ListTopDonorsRow
+		if err := rows.Scan(
+			&i.DonorKey,
+			&i.Donor,
+			&i.Total,
+			&i.Donations,
+			&i.Largest,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
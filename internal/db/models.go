@@ -5,9 +5,32 @@
 package db
 
 import (
+	"database/sql"
 	"time"
 )
 
+type DiscordAuditLog struct {
+	ID            int64
+	UserID        string
+	ChannelID     string
+	RoleIds       string
+	Command       string
+	Line          string
+	Level         string
+	RequiredLevel string
+	Allowed       bool
+	CreatedAt     time.Time
+}
+
+type DiscordPermission struct {
+	ID        int64
+	Kind      string
+	SubjectID string
+	Level     string
+	GrantedBy string
+	UpdatedAt time.Time
+}
+
 type Donation struct {
 	ID        int64
 	User      string
@@ -16,4 +39,73 @@ type Donation struct {
 	Amount    int64
 	Text      string
 	Timestamp time.Time
+	DonorID   sql.NullString
+	SessionID sql.NullInt64
+	Source    string
+}
+
+type Goal struct {
+	ID            int64
+	Channel       string
+	Title         string
+	Target        int64
+	Currency      string
+	StartsAt      time.Time
+	EndsAt        sql.NullTime
+	ClosedAt      sql.NullTime
+	LastMilestone int64
+	CreatedBy     string
+	CreatedAt     time.Time
+}
+
+type StreamSession struct {
+	ID             int64
+	Channel        string
+	Source         string
+	StartedAt      time.Time
+	LastActivityAt time.Time
+	EndedAt        sql.NullTime
+}
+
+type Streamer struct {
+	ID                int64
+	Channel           string
+	BotName           string
+	ValueRegex        string
+	LineFilterContain string
+	LogMessage        bool
+	NotifyThreshold   int64
+	Enabled           bool
+	UpdatedBy         string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type StreamerHistory struct {
+	ID                int64
+	StreamerID        int64
+	Channel           string
+	BotName           string
+	ValueRegex        string
+	LineFilterContain string
+	LogMessage        bool
+	NotifyThreshold   int64
+	Enabled           bool
+	ChangedBy         string
+	ChangedAt         time.Time
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	Webhook        string
+	Channel        string
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int64
+	NextAttemptAt  time.Time
+	LastStatusCode int64
+	LastError      string
+	DeliveredAt    sql.NullTime
+	CreatedAt      time.Time
 }
@@ -0,0 +1,24 @@
+-- name: CreateDiscordAuditEntry :exec
+INSERT INTO discord_audit_log(user_id, channel_id, role_ids, command, line, level, required_level, allowed)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: DeleteDiscordPermission :execrows
+DELETE FROM discord_permission
+WHERE kind = ? AND subject_id = ?;
+
+-- name: ListDiscordAuditLog :many
+SELECT * FROM discord_audit_log
+WHERE (CAST(sqlc.arg(user_id) AS TEXT) = '' OR user_id = sqlc.arg(user_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListDiscordPermissions :many
+SELECT * FROM discord_permission
+ORDER BY kind, subject_id;
+
+-- name: UpsertDiscordPermission :one
+INSERT INTO discord_permission(kind, subject_id, level, granted_by)
+VALUES(?, ?, ?, ?)
+ON CONFLICT(kind, subject_id) DO UPDATE
+SET level = excluded.level, granted_by = excluded.granted_by, updated_at = CURRENT_TIMESTAMP
+RETURNING *;
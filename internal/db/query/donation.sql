@@ -1,14 +1,78 @@
 -- name: CreateDonation :one
-insert into donation(user, channel, send_from, amount, text)    
-VALUES(?, ?, ?, ?, ?)
+insert into donation(user, channel, send_from, amount, text, session_id)    
+VALUES(?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetSumDonationByStreamer :many
 SELECT
     CAST(COALESCE(SUM(d.amount), 0) AS INTEGER) AS amount,
-    CAST(strftime('%Y-%m-%d', MIN(d."timestamp")) AS TEXT)  AS StartingDate,
-    CAST(strftime('%Y-%m-%d', MAX(d."timestamp")) AS TEXT)  AS EndingDate,
+    CAST(datetime(MIN(d."timestamp")) AS TEXT)  AS StartingDate,
+    CAST(datetime(MAX(d."timestamp")) AS TEXT)  AS EndingDate,
     d.channel
 FROM donation d 
-WHERE d."timestamp" BETWEEN ? AND ?
-GROUP BY d.channel;
\ No newline at end of file
+WHERE datetime(d."timestamp") >= datetime(sqlc.arg(from_timestamp)) AND datetime(d."timestamp") < datetime(sqlc.arg(to_timestamp))
+GROUP BY d.channel;
+
+-- name: ListLastDonations :many
+SELECT * FROM donation d
+WHERE (CAST(sqlc.arg(channel) AS TEXT) = '' OR d.channel = sqlc.arg(channel))
+  AND (CAST(sqlc.arg(donor) AS TEXT) = '' OR lower(d.send_from) = lower(sqlc.arg(donor)))
+  AND d.amount >= sqlc.arg(min_amount)
+  AND datetime(d."timestamp") >= datetime(sqlc.arg(since))
+  AND datetime(d."timestamp") < datetime(sqlc.arg(until))
+  AND (
+    CAST(sqlc.arg(cursor_id) AS INTEGER) = 0
+    OR datetime(d."timestamp") < datetime(sqlc.arg(cursor_timestamp))
+    OR (datetime(d."timestamp") = datetime(sqlc.arg(cursor_timestamp)) AND d.id < sqlc.arg(cursor_id))
+  )
+ORDER BY datetime(d."timestamp") DESC, d.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListTopDonors :many
+SELECT
+    CAST(COALESCE(NULLIF(d.donor_id, ''), lower(d.send_from)) AS TEXT) AS donor_key,
+    CAST(MAX(d.send_from) AS TEXT) AS donor,
+    CAST(COALESCE(SUM(d.amount), 0) AS INTEGER) AS total,
+    COUNT(*) AS donations,
+    CAST(COALESCE(MAX(d.amount), 0) AS INTEGER) AS largest
+FROM donation d
+WHERE (CAST(sqlc.arg(channel) AS TEXT) = '' OR d.channel = sqlc.arg(channel))
+  AND datetime(d."timestamp") >= datetime(sqlc.arg(from_timestamp)) AND datetime(d."timestamp") < datetime(sqlc.arg(to_timestamp))
+GROUP BY donor_key
+ORDER BY
+    CASE CAST(sqlc.arg(order_by) AS TEXT)
+        WHEN 'count' THEN COUNT(*)
+        WHEN 'largest' THEN MAX(d.amount)
+        ELSE SUM(d.amount)
+    END DESC,
+    SUM(d.amount) DESC,
+    COUNT(*) DESC,
+    donor_key ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListDonationAmounts :many
+SELECT d.channel, d.amount, d."timestamp"
+FROM donation d
+WHERE (CAST(sqlc.arg(channel) AS TEXT) = '' OR d.channel = sqlc.arg(channel))
+  AND datetime(d."timestamp") >= datetime(sqlc.arg(from_timestamp)) AND datetime(d."timestamp") < datetime(sqlc.arg(to_timestamp))
+ORDER BY d.channel, datetime(d."timestamp");
+
+-- name: ListDonationsForExport :many
+SELECT * FROM donation d
+WHERE (CAST(sqlc.arg(channel) AS TEXT) = '' OR d.channel = sqlc.arg(channel))
+  AND (CAST(sqlc.arg(donor) AS TEXT) = '' OR lower(d.send_from) = lower(sqlc.arg(donor)))
+  AND datetime(d."timestamp") >= datetime(sqlc.arg(from_timestamp)) AND datetime(d."timestamp") < datetime(sqlc.arg(to_timestamp))
+  AND d.id > sqlc.arg(after_id)
+ORDER BY d.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: CountDuplicateDonations :one
+SELECT COUNT(*) FROM donation d
+WHERE d.channel = sqlc.arg(channel)
+  AND lower(d.send_from) = lower(sqlc.arg(send_from))
+  AND d.amount = sqlc.arg(amount)
+  AND datetime(d."timestamp") = datetime(sqlc.arg(donated_at));
+
+-- name: ImportDonation :exec
+INSERT INTO donation(user, channel, send_from, amount, text, "timestamp", source)
+VALUES(?, ?, ?, ?, ?, ?, ?);
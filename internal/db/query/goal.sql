@@ -0,0 +1,41 @@
+-- name: CloseGoal :execrows
+UPDATE goal
+SET closed_at = ?
+WHERE id = ? AND closed_at IS NULL;
+
+-- name: CreateGoal :one
+INSERT INTO goal(channel, title, target, currency, starts_at, ends_at, created_by)
+VALUES(?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetGoalProgress :one
+SELECT
+    g.id, g.channel, g.title, g.target, g.currency, g.starts_at, g.ends_at, g.closed_at, g.last_milestone,
+    CAST(COALESCE(SUM(d.amount), 0) AS INTEGER) AS raised
+FROM goal g
+LEFT JOIN donation d ON d.channel = g.channel
+    AND datetime(d."timestamp") >= datetime(g.starts_at)
+    AND (g.ends_at IS NULL OR datetime(d."timestamp") < datetime(g.ends_at))
+    AND (g.closed_at IS NULL OR datetime(d."timestamp") <= datetime(g.closed_at))
+WHERE g.id = ?
+GROUP BY g.id;
+
+-- name: ListGoalProgress :many
+SELECT
+    g.id, g.channel, g.title, g.target, g.currency, g.starts_at, g.ends_at, g.closed_at, g.last_milestone,
+    CAST(COALESCE(SUM(d.amount), 0) AS INTEGER) AS raised
+FROM goal g
+LEFT JOIN donation d ON d.channel = g.channel
+    AND datetime(d."timestamp") >= datetime(g.starts_at)
+    AND (g.ends_at IS NULL OR datetime(d."timestamp") < datetime(g.ends_at))
+    AND (g.closed_at IS NULL OR datetime(d."timestamp") <= datetime(g.closed_at))
+WHERE (CAST(sqlc.arg(channel) AS TEXT) = '' OR g.channel = sqlc.arg(channel))
+  AND (CAST(sqlc.arg(include_closed) AS BOOLEAN) OR g.closed_at IS NULL)
+GROUP BY g.id
+ORDER BY g.closed_at IS NOT NULL, g.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: SetGoalMilestone :execrows
+UPDATE goal
+SET last_milestone = sqlc.arg(milestone)
+WHERE id = sqlc.arg(id) AND last_milestone < sqlc.arg(milestone);
@@ -0,0 +1,44 @@
+-- name: CreateStreamSession :one
+INSERT INTO stream_session(channel, source, started_at, last_activity_at)
+VALUES(?, ?, ?, ?)
+RETURNING *;
+
+-- name: EndStreamSession :exec
+UPDATE stream_session
+SET last_activity_at = ?, ended_at = ?
+WHERE id = ?;
+
+-- name: GetLastStreamSession :one
+SELECT * FROM stream_session
+WHERE (CAST(sqlc.arg(channel) AS TEXT) = '' OR channel = sqlc.arg(channel))
+ORDER BY datetime(started_at) DESC, id DESC
+LIMIT 1;
+
+-- name: GetOpenStreamSession :one
+SELECT * FROM stream_session
+WHERE channel = ? AND ended_at IS NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListStreamSessions :many
+SELECT
+    s.id,
+    s.channel,
+    s.source,
+    s.started_at,
+    s.last_activity_at,
+    s.ended_at,
+    COUNT(d.id) AS donations,
+    CAST(COALESCE(SUM(d.amount), 0) AS INTEGER) AS total,
+    CAST(COALESCE(MAX(d.amount), 0) AS INTEGER) AS largest
+FROM stream_session s
+LEFT JOIN donation d ON d.session_id = s.id
+WHERE (CAST(sqlc.arg(channel) AS TEXT) = '' OR s.channel = sqlc.arg(channel))
+GROUP BY s.id
+ORDER BY datetime(s.started_at) DESC, s.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: TouchStreamSession :exec
+UPDATE stream_session
+SET last_activity_at = ?
+WHERE id = ?;
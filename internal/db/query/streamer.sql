@@ -0,0 +1,39 @@
+-- name: CountStreamers :one
+SELECT COUNT(*) FROM streamer;
+
+-- name: CreateStreamerHistory :exec
+INSERT INTO streamer_history(streamer_id, channel, bot_name, value_regex, line_filter_contain, log_message, notify_threshold, enabled, changed_by)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetStreamerByChannel :one
+SELECT * FROM streamer
+WHERE channel = ?;
+
+-- name: ListEnabledStreamers :many
+SELECT * FROM streamer
+WHERE enabled = 1
+ORDER BY channel;
+
+-- name: ListStreamerHistory :many
+SELECT * FROM streamer_history
+WHERE channel = ?
+ORDER BY id DESC
+LIMIT ?;
+
+-- name: ListStreamers :many
+SELECT * FROM streamer
+ORDER BY channel;
+
+-- name: UpsertStreamer :one
+INSERT INTO streamer(channel, bot_name, value_regex, line_filter_contain, log_message, notify_threshold, enabled, updated_by)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(channel) DO UPDATE SET
+    bot_name = excluded.bot_name,
+    value_regex = excluded.value_regex,
+    line_filter_contain = excluded.line_filter_contain,
+    log_message = excluded.log_message,
+    notify_threshold = excluded.notify_threshold,
+    enabled = excluded.enabled,
+    updated_by = excluded.updated_by,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
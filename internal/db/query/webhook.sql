@@ -0,0 +1,41 @@
+-- name: CountWebhookDeliveries :many
+SELECT webhook, status, COUNT(*) AS deliveries
+FROM webhook_delivery
+GROUP BY webhook, status;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_delivery(webhook, channel, event, payload, next_attempt_at)
+VALUES(?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_delivery
+WHERE id = ?;
+
+-- name: ListDueWebhookDeliveries :many
+SELECT * FROM webhook_delivery
+WHERE status = 'pending' AND datetime(next_attempt_at) <= datetime(sqlc.arg(now))
+ORDER BY next_attempt_at, id
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_delivery
+WHERE (CAST(sqlc.arg(webhook) AS TEXT) = '' OR webhook = sqlc.arg(webhook))
+  AND (CAST(sqlc.arg(status) AS TEXT) = '' OR status = sqlc.arg(status))
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_delivery
+SET status = 'delivered', attempts = attempts + 1, last_status_code = ?, last_error = '', delivered_at = ?
+WHERE id = ?;
+
+-- name: MarkWebhookFailed :exec
+UPDATE webhook_delivery
+SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_status_code = ?, last_error = ?
+WHERE id = ?;
+
+-- name: RedeliverWebhookDelivery :execrows
+UPDATE webhook_delivery
+SET status = 'pending', attempts = 0, next_attempt_at = ?
+WHERE id = ? AND status != 'pending';
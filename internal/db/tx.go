@@ -0,0 +1,20 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RunInTx runs fn with Queries bound to a new transaction, committing when fn
+// returns nil and rolling back otherwise.
+func RunInTx(ctx context.Context, database *sql.DB, fn func(q *Queries) error) error {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(New(database).WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,84 @@
+package discord
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// SplitArgs splits a command line into arguments like a shell does, keeping
+// their case. Arguments are separated by whitespace, text in single quotes is
+// taken as is and text in double quotes may contain \" and \\. Outside single
+// quotes a backslash escapes a quote, a backslash or whitespace, any other
+// backslash is kept so regexes like \d+ need no quoting.
+func SplitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		// inArg tells an empty quoted argument from no argument
+		inArg  bool
+		quote  rune
+		escape bool
+	)
+	for _, r := range line {
+		switch {
+		case escape:
+			if !isEscapable(r, quote) {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escape = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escape = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case isSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, ErrUnterminatedQuote
+	}
+	if escape {
+		current.WriteRune('\\')
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// isEscapable reports whether a backslash before r escapes it, inside double
+// quotes only a quote and a backslash can be escaped.
+func isEscapable(r rune, quote rune) bool {
+	if quote == '"' {
+		return r == '"' || r == '\\'
+	}
+	return r == '"' || r == '\'' || r == '\\' || isSpace(r)
+}
@@ -0,0 +1,47 @@
+package discord
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		err  error
+	}{
+		{``, nil, nil},
+		{`   `, nil, nil},
+		{`top -by count`, []string{"top", "-by", "count"}, nil},
+		{"  top\t-by   Count \n", []string{"top", "-by", "Count"}, nil},
+		{`-title "Summer goal"`, []string{"-title", "Summer goal"}, nil},
+		{`-title 'Summer goal'`, []string{"-title", "Summer goal"}, nil},
+		{`-title "it's \"on\""`, []string{"-title", `it's "on"`}, nil},
+		{`'a "b" \n'`, []string{`a "b" \n`}, nil},
+		{`pre"fix 1"'and 2'`, []string{"prefix 1and 2"}, nil},
+		{`-regex \d+`, []string{"-regex", `\d+`}, nil},
+		{`-regex "(\d+) CZK"`, []string{"-regex", `(\d+) CZK`}, nil},
+		{`-regex '\d+\.\d+'`, []string{"-regex", `\d+\.\d+`}, nil},
+		{`a\ b c\\d \"e\'`, []string{"a b", `c\d`, `"e'`}, nil},
+		{`"a\\b" "\'"`, []string{`a\b`, `\'`}, nil},
+		{`trailing\`, []string{`trailing\`}, nil},
+		{`-filter "" -bot ''`, []string{"-filter", "", "-bot", ""}, nil},
+		{`""`, []string{""}, nil},
+		{`emoji "💸 dono"`, []string{"emoji", "💸 dono"}, nil},
+		{`-title "open`, nil, ErrUnterminatedQuote},
+		{`-title 'open`, nil, ErrUnterminatedQuote},
+		{`-title "escaped end\"`, nil, ErrUnterminatedQuote},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.line)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("SplitArgs(%q) error %v, want %v", tt.line, err, tt.err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package discord
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// alertBuffer is how many alerts may wait for the Discord API before new
+	// ones are dropped.
+	alertBuffer = 100
+	// Discord's limits.
+	maxContent            = MessageLimit
+	maxEmbedDescription   = 4096
+	maxCommandDescription = 100
+	maxInteractionBytes   = 1 << 20
+	// interactionTimeout is how long the token of an interaction can be used
+	// to reply.
+	interactionTimeout = 15 * time.Minute
+	embedColor         = 0x9146ff
+	userAgent          = "DiscordBot (https://github.com/tartancz/twitch-chat-donation, 1.0)"
+	// argsOption is the single free-form option of every slash command, it
+	// is parsed like the text after a bridge command.
+	argsOption = "args"
+)
+
+// Interaction and response types of the Discord API.
+const (
+	interactionPing               = 1
+	interactionApplicationCommand = 2
+	responsePong                  = 1
+	responseDeferredMessage       = 5
+	optionString                  = 3
+)
+
+// Bot is the backend talking to the Discord API directly. Slash commands
+// arrive at ServeHTTP, which is mounted as the interactions endpoint of the
+// application, replies and alerts are sent over REST.
+type Bot struct {
+	Mux
+	cfg       config.DiscordBotConfig
+	publicKey ed25519.PublicKey
+	client    *http.Client
+	logger    *slog.Logger
+
+	alerts    chan botMessage
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type botMessage struct {
+	channelID string
+	content   string
+}
+
+func NewBot(cfg config.DiscordBotConfig, client *http.Client, logger *slog.Logger) (*Bot, error) {
+	key, err := hex.DecodeString(cfg.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Discord public key")
+	}
+	return &Bot{
+		cfg:       cfg,
+		publicKey: key,
+		client:    client,
+		logger:    logger,
+		alerts:    make(chan botMessage, alertBuffer),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Write posts p to the alert channel.
+func (b *Bot) Write(p []byte) (int, error) {
+	b.post(b.cfg.AlertChannel, p)
+	return len(p), nil
+}
+
+// Alerts returns a writer posting to the Discord channel routed to channel,
+// or to the alert channel. OpsChannel goes to the ops channel when one is set.
+func (b *Bot) Alerts(channel string) io.Writer {
+	if channel == OpsChannel && b.cfg.OpsChannel != "" {
+		return alertWriter{bot: b, channelID: b.cfg.OpsChannel}
+	}
+	if id, ok := b.cfg.Routes[channel]; ok {
+		return alertWriter{bot: b, channelID: id}
+	}
+	return b
+}
+
+type alertWriter struct {
+	bot       *Bot
+	channelID string
+}
+
+func (w alertWriter) Write(p []byte) (int, error) {
+	w.bot.post(w.channelID, p)
+	return len(p), nil
+}
+
+// post queues an alert for Run without blocking the caller, split into
+// messages that fit into maxContent.
+func (b *Bot) post(channelID string, p []byte) {
+	if channelID == "" {
+		return
+	}
+	for _, content := range SplitMessage(strings.TrimSpace(string(p)), maxContent) {
+		select {
+		case <-b.done:
+			return
+		case b.alerts <- botMessage{channelID: channelID, content: content}:
+		default:
+			b.logger.Warn("dropped Discord alert, too many are queued", "channel", channelID)
+			return
+		}
+	}
+}
+
+func (b *Bot) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+// Run registers the slash commands and sends queued alerts until ctx is done
+// or the bot is closed.
+func (b *Bot) Run(ctx context.Context) {
+	if err := b.RegisterCommands(ctx); err != nil {
+		b.logger.Error("failed to register Discord slash commands", "error", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.done:
+			return
+		case msg := <-b.alerts:
+			err := b.do(ctx, http.MethodPost, "/channels/"+msg.channelID+"/messages", messageBody{
+				Content:         truncate(msg.content, maxContent),
+				AllowedMentions: noMentions,
+			})
+			if err != nil {
+				b.logger.Error("failed to send Discord alert", "channel", msg.channelID, "error", err)
+			}
+		}
+	}
+}
+
+type applicationCommand struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []commandOption `json:"options,omitempty"`
+}
+
+type commandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// RegisterCommands replaces the slash commands of the application with one
+// command per top-level command or alias plus help. Subcommands are the first
+// word of args, e.g. /goal args:create tartancz -target 5000.
+func (b *Bot) RegisterCommands(ctx context.Context) error {
+	options := []commandOption{{
+		Type:        optionString,
+		Name:        argsOption,
+		Description: "flags and arguments, e.g. -channel tartancz",
+	}}
+	commands := []applicationCommand{{Name: "help", Description: "List every command, args shows one.", Options: options}}
+	for _, name := range b.Commands() {
+		summary := b.Summary(name)
+		if summary == "" {
+			summary = "Run /help " + name + " to see the subcommands."
+		}
+		commands = append(commands, applicationCommand{
+			Name:        name,
+			Description: shorten(summary, maxCommandDescription),
+			Options:     options,
+		})
+	}
+	path := "/applications/" + b.cfg.ApplicationID + "/commands"
+	if b.cfg.GuildID != "" {
+		path = "/applications/" + b.cfg.ApplicationID + "/guilds/" + b.cfg.GuildID + "/commands"
+	}
+	return b.do(ctx, http.MethodPut, path, commands)
+}
+
+type interaction struct {
+	Type          int    `json:"type"`
+	Token         string `json:"token"`
+	ApplicationID string `json:"application_id"`
+	ChannelID     string `json:"channel_id"`
+	// Member is set for commands sent in a guild, User in direct messages.
+	Member *struct {
+		User  discordUser `json:"user"`
+		Roles []string    `json:"roles"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+type discordUser struct {
+	ID string `json:"id"`
+}
+
+func (in interaction) caller() Caller {
+	caller := Caller{ChannelID: in.ChannelID}
+	switch {
+	case in.Member != nil:
+		caller.UserID = in.Member.User.ID
+		caller.RoleIDs = in.Member.Roles
+	case in.User != nil:
+		caller.UserID = in.User.ID
+	}
+	return caller
+}
+
+type interactionResponse struct {
+	Type int `json:"type"`
+}
+
+// ServeHTTP handles interactions sent by Discord. Commands are acknowledged
+// right away and the reply is sent once the handler is done, handlers may
+// take longer than the three seconds Discord waits for a response.
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInteractionBytes))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if !b.verify(r.Header.Get("X-Signature-Ed25519"), r.Header.Get("X-Signature-Timestamp"), body) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	var in interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+
+	switch in.Type {
+	case interactionPing:
+		writeInteractionResponse(w, responsePong)
+	case interactionApplicationCommand:
+		writeInteractionResponse(w, responseDeferredMessage)
+		go b.runCommand(in)
+	default:
+		http.Error(w, "unsupported interaction type", http.StatusBadRequest)
+	}
+}
+
+func (b *Bot) verify(signature, timestamp string, body []byte) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize || timestamp == "" {
+		return false
+	}
+	return ed25519.Verify(b.publicKey, append([]byte(timestamp), body...), sig)
+}
+
+func writeInteractionResponse(w http.ResponseWriter, responseType int) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(interactionResponse{Type: responseType})
+}
+
+// runCommand runs the command of in like the bridge runs a message and
+// replaces the deferred response with its output.
+func (b *Bot) runCommand(in interaction) {
+	line := in.Data.Name
+	for _, o := range in.Data.Options {
+		if value, ok := o.Value.(string); ok && o.Name == argsOption {
+			line += " " + value
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	b.Execute(in.caller(), line, buf)
+	output := strings.TrimSpace(buf.String())
+	if output == "" {
+		output = "Done."
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	defer cancel()
+	err := b.do(ctx, http.MethodPatch, "/webhooks/"+in.ApplicationID+"/"+in.Token+"/messages/@original", messageBody{
+		Embeds: []embed{{
+			Title:       "/" + in.Data.Name,
+			Description: truncate(output, maxEmbedDescription),
+			Color:       embedColor,
+		}},
+		AllowedMentions: noMentions,
+	})
+	if err != nil {
+		b.logger.Error("failed to reply to Discord command", "command", in.Data.Name, "error", err)
+	}
+}
+
+type messageBody struct {
+	Content         string           `json:"content,omitempty"`
+	Embeds          []embed          `json:"embeds,omitempty"`
+	AllowedMentions *allowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+type embed struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Color       int    `json:"color,omitempty"`
+}
+
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// noMentions keeps donation texts from pinging anyone.
+var noMentions = &allowedMentions{Parse: []string{}}
+
+// do sends body as JSON to the Discord API, waiting once when rate limited.
+func (b *Bot) do(ctx context.Context, method, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(b.cfg.APIURL, "/")+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+b.cfg.Token)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return err
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			var limit struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			json.Unmarshal(msg, &limit)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(limit.RetryAfter * float64(time.Second))):
+			}
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			// the path is left out, interaction paths contain the token
+			return fmt.Errorf("Discord API %s returned %s: %s", method, resp.Status, bytes.TrimSpace(msg))
+		}
+		return nil
+	}
+}
+
+// truncate shortens s to at most limit bytes on a line boundary, closing a
+// code block left open.
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	const suffix = "\n```\n(truncated)"
+	cut := strings.ToValidUTF8(s[:limit-len(suffix)], "")
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	if strings.Count(cut, "```")%2 == 1 {
+		return cut + suffix
+	}
+	return cut + "\n(truncated)"
+}
+
+// shorten cuts the single line s to at most limit bytes.
+func shorten(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return strings.ToValidUTF8(s[:limit-3], "") + "..."
+}
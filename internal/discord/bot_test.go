@@ -0,0 +1,280 @@
+package discord
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// apiRequest is a request received by the mock Discord API.
+type apiRequest struct {
+	Method, Path, Authorization string
+	Body                        []byte
+}
+
+// mockAPI is a local Discord API answering with the queued responses, then
+// with 204, and recording every request.
+type mockAPI struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []func(w http.ResponseWriter)
+	requests  chan apiRequest
+}
+
+func newMockAPI(t *testing.T) *mockAPI {
+	m := &mockAPI{requests: make(chan apiRequest, 16)}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		m.requests <- apiRequest{Method: r.Method, Path: r.URL.Path, Authorization: r.Header.Get("Authorization"), Body: body}
+		m.mu.Lock()
+		var respond func(http.ResponseWriter)
+		if len(m.responses) > 0 {
+			respond, m.responses = m.responses[0], m.responses[1:]
+		}
+		m.mu.Unlock()
+		if respond == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		respond(w)
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockAPI) respond(status int, body string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses = append(m.responses, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	})
+}
+
+func (m *mockAPI) next(t *testing.T) apiRequest {
+	t.Helper()
+	select {
+	case r := <-m.requests:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request reached the mock Discord API")
+		return apiRequest{}
+	}
+}
+
+// newTestBot returns a bot talking to a mock API and the key interactions
+// have to be signed with.
+func newTestBot(t *testing.T, cfg config.DiscordBotConfig) (*Bot, *mockAPI, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newMockAPI(t)
+	cfg.Token = "token"
+	cfg.ApplicationID = "app"
+	cfg.PublicKey = hex.EncodeToString(public)
+	cfg.APIURL = api.URL + "/"
+	b, err := NewBot(cfg, api.Client(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(b.Close)
+	return b, api, private
+}
+
+func TestBotRegisterCommands(t *testing.T) {
+	b, api, _ := newTestBot(t, config.DiscordBotConfig{GuildID: "guild"})
+	b.AddCommand(Command{Name: "donation"}, nil)
+	b.AddCommand(Command{Name: "donation top", Aliases: []string{"top"}, Summary: strings.Repeat("Get the top donors. ", 10)}, func(DiscordMessageArgs, io.Writer) {})
+
+	if err := b.RegisterCommands(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	r := api.next(t)
+	if r.Method != http.MethodPut || r.Path != "/applications/app/guilds/guild/commands" || r.Authorization != "Bot token" {
+		t.Fatalf("request %s %s with %q", r.Method, r.Path, r.Authorization)
+	}
+	var commands []applicationCommand
+	if err := json.Unmarshal(r.Body, &commands); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.Name)
+		if len(c.Description) > maxCommandDescription || c.Description == "" {
+			t.Errorf("%s has the description %q", c.Name, c.Description)
+		}
+		if len(c.Options) != 1 || c.Options[0].Name != argsOption || c.Options[0].Type != optionString {
+			t.Errorf("%s has the options %+v", c.Name, c.Options)
+		}
+	}
+	if got := strings.Join(names, " "); got != "help donation top" {
+		t.Errorf("registered %s", got)
+	}
+}
+
+// signedRequest returns an interaction request signed with key.
+func signedRequest(key ed25519.PrivateKey, body string) *http.Request {
+	const timestamp = "1700000000"
+	r := httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(body))
+	r.Header.Set("X-Signature-Timestamp", timestamp)
+	r.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	return r
+}
+
+func TestBotVerifiesInteractions(t *testing.T) {
+	b, _, key := newTestBot(t, config.DiscordBotConfig{})
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const ping = `{"type":1}`
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		want    int
+	}{
+		{"valid", func() *http.Request { return signedRequest(key, ping) }, http.StatusOK},
+		{"other key", func() *http.Request { return signedRequest(otherKey, ping) }, http.StatusUnauthorized},
+		{"tampered body", func() *http.Request {
+			r := signedRequest(key, ping)
+			r.Body = io.NopCloser(strings.NewReader(`{"type":2}`))
+			return r
+		}, http.StatusUnauthorized},
+		{"missing signature", func() *http.Request {
+			r := signedRequest(key, ping)
+			r.Header.Del("X-Signature-Ed25519")
+			return r
+		}, http.StatusUnauthorized},
+		{"missing timestamp", func() *http.Request {
+			r := signedRequest(key, ping)
+			r.Header.Del("X-Signature-Timestamp")
+			return r
+		}, http.StatusUnauthorized},
+		{"unknown type", func() *http.Request { return signedRequest(key, `{"type":99}`) }, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			b.ServeHTTP(rr, tt.request())
+			if rr.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rr.Code, tt.want, rr.Body)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	b.ServeHTTP(rr, signedRequest(key, ping))
+	var resp interactionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Type != responsePong {
+		t.Errorf("PING answered with %s", rr.Body)
+	}
+}
+
+func TestBotDeferredReply(t *testing.T) {
+	b, api, key := newTestBot(t, config.DiscordBotConfig{})
+	b.AddCommand(Command{Name: "donation top", Aliases: []string{"top"}}, func(args DiscordMessageArgs, w io.Writer) {
+		fmt.Fprintf(w, "%s %s by %s in %s", args.CommandName, strings.Join(args.Args, ","), args.Caller.UserID, args.Caller.ChannelID)
+	})
+
+	body := `{"type":2,"token":"tok","application_id":"app","channel_id":"chan",` +
+		`"member":{"user":{"id":"user"},"roles":["role"]},` +
+		`"data":{"name":"top","options":[{"name":"args","value":"-by \"count\""}]}}`
+	rr := httptest.NewRecorder()
+	b.ServeHTTP(rr, signedRequest(key, body))
+	var resp interactionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Type != responseDeferredMessage {
+		t.Fatalf("command answered with %s", rr.Body)
+	}
+
+	r := api.next(t)
+	if r.Method != http.MethodPatch || r.Path != "/webhooks/app/tok/messages/@original" {
+		t.Fatalf("reply sent as %s %s", r.Method, r.Path)
+	}
+	var reply messageBody
+	if err := json.Unmarshal(r.Body, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Embeds) != 1 || reply.Embeds[0].Title != "/top" {
+		t.Fatalf("reply %s", r.Body)
+	}
+	if want := "donation top -by,count by user in chan"; reply.Embeds[0].Description != want {
+		t.Errorf("reply %q, want %q", reply.Embeds[0].Description, want)
+	}
+	if reply.AllowedMentions == nil || len(reply.AllowedMentions.Parse) != 0 {
+		t.Errorf("reply may mention: %s", r.Body)
+	}
+}
+
+func TestBotRateLimit(t *testing.T) {
+	b, api, _ := newTestBot(t, config.DiscordBotConfig{})
+	ctx := context.Background()
+
+	api.respond(http.StatusTooManyRequests, `{"retry_after":0.05}`)
+	start := time.Now()
+	if err := b.do(ctx, http.MethodPost, "/channels/1/messages", messageBody{Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("retried after %v, want at least retry_after", waited)
+	}
+	for range 2 {
+		if r := api.next(t); r.Path != "/channels/1/messages" {
+			t.Errorf("request to %s", r.Path)
+		}
+	}
+
+	// only one retry
+	api.respond(http.StatusTooManyRequests, `{"retry_after":0.01}`)
+	api.respond(http.StatusTooManyRequests, `{"retry_after":0.01}`)
+	err := b.do(ctx, http.MethodPost, "/channels/1/messages", messageBody{Content: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("err = %v, want the second 429", err)
+	}
+
+	// the wait ends with the context
+	api.respond(http.StatusTooManyRequests, `{"retry_after":60}`)
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := b.do(ctx, http.MethodPost, "/channels/1/messages", messageBody{Content: "hi"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context error", err)
+	}
+}
+
+func TestBotAlertRouting(t *testing.T) {
+	b, api, _ := newTestBot(t, config.DiscordBotConfig{
+		AlertChannel: "alerts",
+		OpsChannel:   "ops",
+		Routes:       map[string]string{"#tartancz": "tartancz"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+	if r := api.next(t); r.Method != http.MethodPut {
+		t.Fatalf("first request %s %s, want the command registration", r.Method, r.Path)
+	}
+
+	fmt.Fprint(b.Alerts("#tartancz"), "routed")
+	fmt.Fprint(b.Alerts("#other"), "default")
+	fmt.Fprint(b.Alerts(OpsChannel), "health")
+	for _, want := range []string{"/channels/tartancz/messages", "/channels/alerts/messages", "/channels/ops/messages"} {
+		if r := api.next(t); r.Path != want {
+			t.Errorf("alert sent to %s, want %s", r.Path, want)
+		}
+	}
+}
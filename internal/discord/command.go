@@ -0,0 +1,172 @@
+package discord
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type FlagKind int
+
+const (
+	StringFlag FlagKind = iota
+	Int64Flag
+	BoolFlag
+)
+
+// Flag declares a flag of a command. Default is the flag's default written
+// as it would be passed, empty for the zero value.
+type Flag struct {
+	Name    string
+	Kind    FlagKind
+	Default string
+	Usage   string
+}
+
+// Command declares the arguments and flags of a command, its help is
+// generated from them.
+type Command struct {
+	// Name is the command as typed, e.g. "top" or "goal create".
+	Name string
+	// Args are the positional arguments and where the flags go, e.g.
+	// "<channel> [flags] [title]". "[flags]" is used when empty.
+	Args    string
+	Summary string
+	Flags   []Flag
+	// Aliases are other names of the command, each from the root, e.g.
+	// "last" for "donation last".
+	Aliases []string
+	// Permission is the level needed to run the command and its
+	// subcommands.
+	Permission Level
+}
+
+// Values are the parsed flags of a command. The embedded FlagSet gives the
+// arguments left after the flags.
+type Values struct {
+	*flag.FlagSet
+	set map[string]bool
+}
+
+// FlagSet returns a flag set with the flags of c, errors and the help go to
+// w.
+func (c Command) FlagSet(w io.Writer) *flag.FlagSet {
+	f := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	f.SetOutput(w)
+	f.Usage = func() {
+		fmt.Fprintln(w, c.Help())
+	}
+	for _, fl := range c.Flags {
+		switch fl.Kind {
+		case Int64Flag:
+			value, _ := strconv.ParseInt(fl.Default, 10, 64)
+			f.Int64(fl.Name, value, fl.Usage)
+		case BoolFlag:
+			value, _ := strconv.ParseBool(fl.Default)
+			f.Bool(fl.Name, value, fl.Usage)
+		default:
+			f.String(fl.Name, fl.Default, fl.Usage)
+		}
+	}
+	return f
+}
+
+// Parse parses args with the flags of c. Flag errors and -help are written
+// to w together with the help of c and returned.
+func (c Command) Parse(args []string, w io.Writer) (*Values, error) {
+	return ParseFlagSet(c.FlagSet(w), args)
+}
+
+// ParseFlagSet parses args with a flag set made by Command.FlagSet, for
+// callers adding flags of their own.
+func ParseFlagSet(f *flag.FlagSet, args []string) (*Values, error) {
+	if err := f.Parse(args); err != nil {
+		return nil, err
+	}
+	v := &Values{FlagSet: f, set: make(map[string]bool)}
+	f.Visit(func(fl *flag.Flag) {
+		v.set[fl.Name] = true
+	})
+	return v, nil
+}
+
+func (v *Values) String(name string) string {
+	return v.get(name).(string)
+}
+
+func (v *Values) Int64(name string) int64 {
+	return v.get(name).(int64)
+}
+
+func (v *Values) Bool(name string) bool {
+	return v.get(name).(bool)
+}
+
+// IsSet reports whether the flag was passed, even with its default.
+func (v *Values) IsSet(name string) bool {
+	return v.set[name]
+}
+
+// get panics for flags the command does not declare, like the flag package
+// does for flags defined twice.
+func (v *Values) get(name string) any {
+	fl := v.Lookup(name)
+	if fl == nil {
+		panic(fmt.Sprintf("%s: undeclared flag -%s", v.Name(), name))
+	}
+	return fl.Value.(flag.Getter).Get()
+}
+
+// Help returns the summary of c followed by its usage and flags.
+func (c Command) Help() string {
+	summary := c.Summary
+	c.Summary = ""
+	return Help(summary, c)
+}
+
+// Help returns the help of a command with subcommands: summary followed by
+// the usage, summary and flags of every subcommand.
+func Help(summary string, commands ...Command) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString(summary)
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tb, "\n  %s", c.usage())
+		if c.Summary != "" {
+			fmt.Fprintf(tb, " - %s", c.Summary)
+		}
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(tb, " (alias %s)", strings.Join(c.Aliases, ", "))
+		}
+		for _, fl := range c.Flags {
+			fmt.Fprintf(tb, "\n    -%s\t%s", fl.synopsis(), fl.Usage)
+			if fl.Default != "" && fl.Kind != BoolFlag {
+				fmt.Fprintf(tb, " (default %s)", fl.Default)
+			}
+		}
+	}
+	tb.Flush()
+	return buf.String()
+}
+
+func (c Command) usage() string {
+	args := c.Args
+	if args == "" && len(c.Flags) > 0 {
+		args = "[flags]"
+	}
+	return strings.TrimSpace(c.Name + " " + args)
+}
+
+func (fl Flag) synopsis() string {
+	switch fl.Kind {
+	case Int64Flag:
+		return fl.Name + " N"
+	case BoolFlag:
+		return fl.Name
+	}
+	return fl.Name + " X"
+}
@@ -1,194 +1,322 @@
 package discord
 
 import (
+	"TwitchDonoCalculator/internal/config"
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
-	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var DefaultServer = NewServer()
+// Backend is how the application talks to Discord: through a relay process
+// (Server) or to the Discord API directly (Bot). Writing to a backend posts
+// an alert to the default channel.
+type Backend interface {
+	io.Writer
+	AddCommand(command Command, handler HandlerFunc)
+	SetGuard(guard Guard)
+	// Alerts returns a writer for alerts about a Twitch channel, routed to
+	// the Discord channel configured for it.
+	Alerts(channel string) io.Writer
+	Close()
+}
+
+// OpsChannel is passed to Backend.Alerts for alerts about the health of the
+// application instead of a Twitch channel.
+const OpsChannel = "ops"
 
 type DiscordMessageArgs struct {
-	Raw         string
-	Args        []string
+	Raw  string
+	Args []string
+	// CommandName is the full name of the command, e.g. "goal create".
 	CommandName string
+	Caller      Caller
+	// Level is the level of Caller.
+	Level Level
 }
 
-type DiscordMessageHandler interface {
-	HandleMessage(args DiscordMessageArgs, writer io.Writer)
-	GetHelpMessage() string
-}
+// HandlerFunc runs a command, writing its reply to writer.
+type HandlerFunc func(args DiscordMessageArgs, writer io.Writer)
 
-type DiscordMessageHandlerStruct struct {
-	HandleFunc func(args DiscordMessageArgs, writer io.Writer)
-	HelpMessage string
-}
+// Server is the bridge backend, it speaks a line protocol to a relay process
+// that forwards Discord messages, see ProtocolVersion.
+type Server struct {
+	Mux
+	onMessage func(string, io.Writer)
+	// outbox keeps messages for the relay while it is unreachable.
+	outbox    chan Message
+	done      chan struct{}
+	closeOnce sync.Once
+	running   atomic.Bool
+	// protocol and name are set by RunServer before it connects.
+	protocol string
+	name     string
 
-func (f DiscordMessageHandlerStruct) HandleMessage(args DiscordMessageArgs, writer io.Writer) {
-	if f.HandleFunc != nil {
-		f.HandleFunc(args, writer)
-	}
+	mu        sync.Mutex
+	delimiter string
+	// capabilities are the v1 capabilities both sides support.
+	capabilities []string
 }
 
-func (f DiscordMessageHandlerStruct) GetHelpMessage() string {
-	if f.HelpMessage != "" {
-		return f.HelpMessage
-	}
-	return "No help message provided."
-}
+const (
+	// outboxSize bounds how many messages wait for the relay.
+	outboxSize = 256
+	// writeTimeout is how long Write waits for room in the outbox.
+	writeTimeout      = 5 * time.Second
+	connWriteTimeout  = 10 * time.Second
+	dialTimeout       = 10 * time.Second
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
 
-type Server struct {
-	messageChan chan []byte
-	onMessage   func(string, io.Writer)
-	handlers    map[string]DiscordMessageHandler
-	conn        net.Conn
-	delimiter   string
-}
+// ErrClosed is returned when writing to a closed Server.
+var ErrClosed = errors.New("discord: server closed")
 
 func NewServer() *Server {
 	return &Server{
-		messageChan: make(chan []byte),
+		outbox: make(chan Message, outboxSize),
+		done:   make(chan struct{}),
 	}
 }
 
+// Write queues p as an alert, waiting at most writeTimeout for room. Text
+// longer than MessageLimit is split into several messages, see SplitMessage.
 func (w *Server) Write(p []byte) (n int, err error) {
-	if w.conn == nil {
-		return 0, nil
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	return w.WriteContext(ctx, p)
+}
+
+// WriteContext queues p as an alert, waiting for room until ctx is done.
+func (w *Server) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	return w.writeAlert(ctx, "", p)
+}
+
+func (w *Server) writeAlert(ctx context.Context, channel string, p []byte) (n int, err error) {
+	if !w.running.Load() {
+		// no relay configured, there is nobody to send to
+		return len(p), nil
+	}
+	for _, text := range SplitMessage(strings.TrimSpace(string(p)), MessageLimit) {
+		if err := w.send(ctx, Message{Type: TypeAlert, Text: text, Channel: channel}); err != nil {
+			return 0, err
+		}
 	}
+	return len(p), nil
+}
 
-	msg := make([]byte, len(p), len(p)+len(w.delimiter)+1)
-	copy(msg, p)
+// send queues m for the relay.
+func (w *Server) send(ctx context.Context, m Message) error {
+	select {
+	case <-w.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case w.outbox <- m:
+		return nil
+	case <-w.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	msg = append(bytes.TrimSpace(msg), []byte("\n"+w.delimiter+"\n")...)
+// Alerts returns a writer for alerts about channel. The channel is only sent
+// to v1 relays that route alerts, the legacy protocol has a single channel.
+func (w *Server) Alerts(channel string) io.Writer {
+	return serverAlertWriter{server: w, channel: channel}
+}
 
-	w.messageChan <- msg
+type serverAlertWriter struct {
+	server  *Server
+	channel string
+}
 
-	return len(p), nil
+func (a serverAlertWriter) Write(p []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	return a.server.writeAlert(ctx, a.channel, p)
 }
 
+// Close stops RunServer, messages still waiting for the relay are dropped.
+// It is safe to call Close more than once and concurrently with Write.
 func (w *Server) Close() {
-	if w.messageChan != nil {
-		close(w.messageChan)
-		w.messageChan = nil
-	}
-
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
 }
 
 func (w *Server) SetOnMessage(f func(string, io.Writer)) {
 	w.onMessage = f
 }
 
-func (w *Server) AddHandler(command string, handler DiscordMessageHandler) {
-	if w.handlers == nil {
-		w.handlers = make(map[string]DiscordMessageHandler)
-	}
-	command = strings.ToLower(command)
-	w.handlers[command] = handler
-}
-
 func (w *Server) HandleMessage(line string) {
 	if w.onMessage != nil {
 		w.onMessage(line, w)
 	}
 
 	line = strings.TrimSpace(line)
-	args := strings.Split(strings.ToLower(line), " ")
-
-	if len(args) < 3 || args[2] == "help" {
-		fmt.Fprint(w, w.GeneretaHelp())
+	args, err := SplitArgs(line)
+	if err != nil {
+		fmt.Fprintf(w, "Invalid command: %v\n", err)
 		return
 	}
-	command := args[2]
-	if handler, exists := w.handlers[command]; exists {
-		handler.HandleMessage(DiscordMessageArgs{
-			Raw:  line,
-			Args: args[3:],
-			CommandName: command,
-		}, w)
-	} else {
-		fmt.Fprintf(w, "Unknown command: %s\n", command)
-		fmt.Fprint(w, w.GeneretaHelp())
-	}
-}
-func (w *Server) GeneretaHelp() string {
-	var helpMessage strings.Builder
-	for command, handler := range w.handlers {
-		helpMessage.WriteString(fmt.Sprintf("%s: %s\n-----------------\n", command, handler.GetHelpMessage()))
+
+	// legacy lines are "<prefix> <name> <command> [args]" with the name sent
+	// in SET_NAME, the command is the third word when the name is missing
+	start := min(2, len(args))
+	for i, arg := range args {
+		if w.name != "" && strings.EqualFold(arg, w.name) {
+			start = i + 1
+			break
+		}
 	}
-	if helpMessage.Len() == 0 {
-		return "No commands available."
+	// the legacy protocol doesn't tell who sent the line, the reply is sent
+	// at once so it can be split into messages on line boundaries
+	buf := &bytes.Buffer{}
+	w.Dispatch(Caller{}, args[start:], line, buf)
+	if buf.Len() > 0 {
+		w.Write(buf.Bytes())
 	}
-	return helpMessage.String()
 }
 
-func (w *Server) RunServer(programName string) {
-	if w.conn != nil {
+// RunServer keeps a connection to the relay at cfg.Host:cfg.Port until ctx
+// is done or the server is closed, reconnecting with backoff whenever the
+// connection fails or drops. It returns right away when no port is set.
+func (w *Server) RunServer(ctx context.Context, programName string, cfg config.DiscordConfig) {
+	if cfg.Port == "" || !w.running.CompareAndSwap(false, true) {
 		return
 	}
+	w.protocol = cfg.Protocol
+	w.name = programName
 
-	port, exists := os.LookupEnv("DISCORD_BOT_SERVER_PORT")
-	if !exists {
-		return
-	}
-	host := getEnv("DISCORD_BOT_SERVER_HOST", "")
-	var conn net.Conn
-	var err error
-	timeout := time.NewTimer(60 * time.Second)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-w.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-connectLoop:
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	delay := minReconnectDelay
+	// pending is a message taken from the outbox that could not be written
+	// yet, it is sent first after reconnecting.
+	var pending *Message
 	for {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			slog.Info("connected to Discord relay", "addr", addr)
+			delay = minReconnectDelay
+			err = w.serve(ctx, conn, programName, &pending)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Discord relay connection lost, reconnecting", "addr", addr, "error", err, "retry", delay)
 		select {
-		case <-time.After(5 * time.Second):
-			conn, err = net.Dial("tcp", net.JoinHostPort(host, port))
-			if err != nil {
-				fmt.Printf("Failed to connect to server: %v, retrying in 5 seconds...\n", err)
-				continue
-			}
-			break connectLoop
-		case <-timeout.C:
-			fmt.Println("Connection timed out after 60 seconds")
-			time.Sleep(60 * time.Minute)
-			timeout.Reset(60 * time.Second)
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
 		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// serve runs the protocol on conn until it fails or ctx is done.
+func (w *Server) serve(ctx context.Context, conn net.Conn, programName string, pending **Message) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+
+	w.mu.Lock()
+	w.capabilities = nil
+	w.mu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(connWriteTimeout))
+	var err error
+	if w.protocol == config.BridgeProtocolV1 {
+		_, err = conn.Write(encodeFrame(Message{
+			Type:         TypeHello,
+			Version:      ProtocolVersion,
+			Name:         programName,
+			Capabilities: capabilities,
+		}))
+	} else {
+		_, err = fmt.Fprintf(conn, "SET_NAME:%s\nGET_DELIMITER:\n", programName)
+	}
+	if err != nil {
+		return err
 	}
-	defer func() {
-		conn.Close()
-		w.conn = nil
-	}()
-	w.conn = conn
-	fmt.Fprintf(conn, "SET_NAME:%s\n", programName)
-	fmt.Fprintf(conn, "GET_DELIMITER:\n")
 
+	readErr := make(chan error, 1)
 	go func() {
-		reader := bufio.NewReader(conn)
+		readErr <- w.read(ctx, conn)
+	}()
 
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				return
-			}
-			if strings.HasPrefix(line, "DELIMITER:") {
-				w.delimiter = strings.TrimSpace(strings.TrimPrefix(line, "DELIMITER:"))
-				continue
+	for {
+		if *pending == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-readErr:
+				return err
+			case m := <-w.outbox:
+				*pending = &m
 			}
-			w.HandleMessage(line)
 		}
+		conn.SetWriteDeadline(time.Now().Add(connWriteTimeout))
+		if _, err := conn.Write(w.encode(**pending)); err != nil {
+			return err
+		}
+		*pending = nil
+	}
+}
 
-	}()
-
-	for val := range w.messageChan {
-		conn.Write(val)
+// read handles everything the relay sends until the connection fails.
+func (w *Server) read(ctx context.Context, conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if w.protocol == config.BridgeProtocolV1 {
+			w.handleFrame(ctx, []byte(line))
+			continue
+		}
+		if strings.HasPrefix(line, "DELIMITER:") {
+			w.mu.Lock()
+			w.delimiter = strings.TrimSpace(strings.TrimPrefix(line, "DELIMITER:"))
+			w.mu.Unlock()
+			continue
+		}
+		w.HandleMessage(line)
 	}
 }
 
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// encode frames m for the protocol of the current connection.
+func (w *Server) encode(m Message) []byte {
+	if w.protocol == config.BridgeProtocolV1 {
+		if m.Type == TypeAlert && !w.hasCapability(CapabilityAlertChannel) {
+			m.Channel = ""
+		}
+		return encodeFrame(m)
 	}
-	return defaultValue
+	w.mu.Lock()
+	delimiter := w.delimiter
+	w.mu.Unlock()
+	return []byte(m.Text + "\n" + delimiter + "\n")
 }
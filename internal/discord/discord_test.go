@@ -0,0 +1,241 @@
+package discord
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRelay is a relay process on a local port the Server connects to.
+type fakeRelay struct {
+	ln   net.Listener
+	port string
+}
+
+func newFakeRelay(t *testing.T) *fakeRelay {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return &fakeRelay{ln: ln, port: port}
+}
+
+// relayConn is a connection from the Server as seen by the relay.
+type relayConn struct {
+	*net.TCPConn
+	r *bufio.Reader
+}
+
+// accept waits for the Server to connect.
+func (f *fakeRelay) accept(t *testing.T) *relayConn {
+	t.Helper()
+	f.ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := f.ln.Accept()
+	if err != nil {
+		t.Fatalf("the server did not connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &relayConn{TCPConn: conn.(*net.TCPConn), r: bufio.NewReader(conn)}
+}
+
+func (c *relayConn) readLine(t *testing.T) string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading from the server: %v", err)
+	}
+	return strings.TrimSuffix(line, "\n")
+}
+
+// readFrame reads a v1 frame.
+func (c *relayConn) readFrame(t *testing.T) Message {
+	t.Helper()
+	line := c.readLine(t)
+	var m Message
+	if err := json.Unmarshal([]byte(line), &m); err != nil {
+		t.Fatalf("frame %q: %v", line, err)
+	}
+	return m
+}
+
+func (c *relayConn) send(t *testing.T, line string) {
+	t.Helper()
+	if _, err := c.Write([]byte(line + "\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startServer runs a Server connected to relay with the protocol and returns
+// it once RunServer is running. Cleanup closes it and waits for RunServer.
+func startServer(t *testing.T, relay *fakeRelay, protocol string) *Server {
+	t.Helper()
+	s := NewServer()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.RunServer(context.Background(), "TwitchDonoCalculator", config.DiscordConfig{Host: "127.0.0.1", Port: relay.port, Protocol: protocol})
+	}()
+	t.Cleanup(func() {
+		s.Close()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("RunServer did not return after Close")
+		}
+	})
+	return s
+}
+
+func TestServerKeepsAlertsAcrossReconnects(t *testing.T) {
+	relay := newFakeRelay(t)
+	s := startServer(t, relay, config.BridgeProtocolV1)
+
+	conn := relay.accept(t)
+	if m := conn.readFrame(t); m.Type != TypeHello {
+		t.Fatalf("first frame %+v, want hello", m)
+	}
+	fmt.Fprint(s, "before the drop")
+	if m := conn.readFrame(t); m.Text != "before the drop" {
+		t.Errorf("alert %+v", m)
+	}
+
+	// the server sees the connection end and closes its side
+	conn.CloseWrite()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.r.ReadString('\n'); err == nil {
+		t.Fatal("the server kept the dropped connection")
+	}
+
+	fmt.Fprint(s, "while disconnected")
+	conn = relay.accept(t)
+	if m := conn.readFrame(t); m.Type != TypeHello {
+		t.Fatalf("first frame after reconnecting %+v, want hello", m)
+	}
+	if m := conn.readFrame(t); m.Type != TypeAlert || m.Text != "while disconnected" {
+		t.Errorf("alert after reconnecting %+v", m)
+	}
+}
+
+func TestServerSendsPendingMessageFirst(t *testing.T) {
+	s := NewServer()
+	s.protocol = config.BridgeProtocolLegacy
+	var pending *Message
+
+	// the relay goes away while the server writes the alert
+	server, relay := net.Pipe()
+	errc := make(chan error, 1)
+	go func() { errc <- s.serve(context.Background(), server, "TwitchDonoCalculator", &pending) }()
+	handshake := "SET_NAME:TwitchDonoCalculator\nGET_DELIMITER:\n"
+	buf := make([]byte, len(handshake))
+	if _, err := io.ReadFull(relay, buf); err != nil || string(buf) != handshake {
+		t.Fatalf("handshake %q, %v", buf, err)
+	}
+	s.outbox <- Message{Type: TypeAlert, Text: "first"}
+	// the pipe is unbuffered, so the alert is being written once a byte of
+	// it arrives
+	if _, err := relay.Read(buf[:1]); err != nil {
+		t.Fatal(err)
+	}
+	relay.Close()
+	if err := <-errc; err == nil {
+		t.Fatal("serve returned no error for a closed connection")
+	}
+	if pending == nil || pending.Text != "first" {
+		t.Fatalf("pending = %+v, want the alert that was not written", pending)
+	}
+
+	s.outbox <- Message{Type: TypeAlert, Text: "second"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, relay = net.Pipe()
+	defer relay.Close()
+	go s.serve(ctx, server, "TwitchDonoCalculator", &pending)
+	r := bufio.NewReader(relay)
+	var lines []string
+	for range 4 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if got := strings.Join(lines[2:], ","); got != "first," {
+		t.Errorf("after reconnecting got %q, want the pending alert first", got)
+	}
+	line, _ := r.ReadString('\n')
+	if line != "second\n" {
+		t.Errorf("then %q, want the queued alert", line)
+	}
+}
+
+func TestServerCloseWhileWriting(t *testing.T) {
+	relay := newFakeRelay(t)
+	s := startServer(t, relay, config.BridgeProtocolLegacy)
+	conn := relay.accept(t)
+	go func() {
+		// read everything so writes don't block on the connection
+		for {
+			if _, err := conn.r.ReadString('\n'); err != nil {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if _, err := s.Write([]byte("alert")); err != nil && !errors.Is(err, ErrClosed) {
+					t.Errorf("Write() = %v, want nil or ErrClosed", err)
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	s.Close()
+	wg.Wait()
+	if _, err := s.Write([]byte("alert")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write() after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestServerWriteContextFullOutbox(t *testing.T) {
+	s := NewServer()
+	// as if RunServer were waiting for the relay
+	s.running.Store(true)
+	for range outboxSize {
+		if _, err := s.WriteContext(context.Background(), []byte("alert")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if _, err := s.WriteContext(ctx, []byte("one too many")); !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteContext() = %v, want context.Canceled", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("WriteContext waited despite the cancelled context")
+	}
+	if len(s.outbox) != outboxSize {
+		t.Errorf("outbox has %d messages, want %d", len(s.outbox), outboxSize)
+	}
+}
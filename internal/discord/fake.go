@@ -0,0 +1,94 @@
+package discord
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Recorder is a Backend that keeps every alert instead of sending it and runs
+// commands on demand, for local runs and tests without Discord.
+type Recorder struct {
+	Mux
+	mu     sync.Mutex
+	alerts []RecordedAlert
+	closed bool
+}
+
+// RecordedAlert is an alert written to a Recorder. Channel is the Twitch
+// channel passed to Alerts, empty for the default channel.
+type RecordedAlert struct {
+	Channel string
+	Text    string
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.record("", p)
+	return len(p), nil
+}
+
+func (r *Recorder) Alerts(channel string) io.Writer {
+	return recorderAlertWriter{recorder: r, channel: channel}
+}
+
+type recorderAlertWriter struct {
+	recorder *Recorder
+	channel  string
+}
+
+func (w recorderAlertWriter) Write(p []byte) (int, error) {
+	w.recorder.record(w.channel, p)
+	return len(p), nil
+}
+
+func (r *Recorder) record(channel string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, RecordedAlert{Channel: channel, Text: strings.TrimSpace(string(p))})
+}
+
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+}
+
+// Closed reports whether Close was called.
+func (r *Recorder) Closed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// Recorded returns the alerts written so far, oldest first.
+func (r *Recorder) Recorded() []RecordedAlert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedAlert(nil), r.alerts...)
+}
+
+// Reset forgets the recorded alerts.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = nil
+}
+
+// Run runs the command line like a relay command starting at the command
+// name, e.g. "top -by count", and returns its output. The caller is
+// anonymous.
+func (r *Recorder) Run(line string) string {
+	return r.RunAs(Caller{}, line)
+}
+
+// RunAs is Run for caller.
+func (r *Recorder) RunAs(caller Caller, line string) string {
+	buf := &bytes.Buffer{}
+	r.Execute(caller, line, buf)
+	return buf.String()
+}
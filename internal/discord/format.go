@@ -0,0 +1,132 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// MessageLimit is the maximum length of a Discord message.
+const MessageLimit = 2000
+
+const codeFence = "```"
+
+// SplitMessage splits text into messages of at most limit bytes, on line
+// boundaries unless a single line is longer. A code block open at the end of
+// a message is closed there and opened again at the start of the next one.
+func SplitMessage(text string, limit int) []string {
+	if len(text) <= limit {
+		return []string{text}
+	}
+	reopen := codeFence + "\n"
+	var (
+		messages []string
+		current  strings.Builder
+		// open tells whether current ends inside a code block
+		open bool
+	)
+	flush := func() {
+		if open {
+			current.WriteString(codeFence)
+		}
+		if message := strings.TrimSpace(current.String()); message != "" {
+			messages = append(messages, message)
+		}
+		current.Reset()
+		if open {
+			current.WriteString(reopen)
+		}
+	}
+	// a line must fit between the fences reopening and closing a block
+	for _, line := range splitLines(text, limit-len(reopen)-len(codeFence)) {
+		after := open != (strings.Count(line, codeFence)%2 == 1)
+		closing := 0
+		if after {
+			closing = len(codeFence)
+		}
+		if current.Len()+len(line)+closing > limit {
+			flush()
+		}
+		current.WriteString(line)
+		open = after
+	}
+	open = false
+	flush()
+	return messages
+}
+
+// splitLines splits text after every newline and cuts lines longer than max
+// bytes.
+func splitLines(text string, max int) []string {
+	var lines []string
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for len(line) > max {
+			cut := max
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Table renders rows in a code block that fits into one Discord message, the
+// rows that don't fit are left for the next page.
+type Table struct {
+	// Header and Rows are cells separated by tabs.
+	Header string
+	Rows   []string
+	// More tells there are rows after Rows, e.g. when a query was limited.
+	More bool
+	// NextPage returns the footer pointing to the page starting at row next
+	// of Rows, e.g. with a page token. Without it the footer counts the rows
+	// left out.
+	NextPage func(next int) string
+	// Limit is the maximum length of the reply, MessageLimit when zero.
+	Limit int
+}
+
+// Render returns as many rows as fit into the limit, followed by the footer
+// when rows were left out. The first row is always shown.
+func (t Table) Render() string {
+	limit := t.Limit
+	if limit == 0 {
+		limit = MessageLimit
+	}
+	// the length only grows with the rows, find the most that fit
+	n := sort.Search(len(t.Rows), func(i int) bool {
+		return len(t.page(i+1)) > limit
+	})
+	return t.page(max(n, min(1, len(t.Rows))))
+}
+
+// page renders the first n rows.
+func (t Table) page(n int) string {
+	buf := &bytes.Buffer{}
+	tb := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tb, t.Header)
+	for _, row := range t.Rows[:n] {
+		fmt.Fprintln(tb, row)
+	}
+	tb.Flush()
+	page := codeFence + buf.String() + codeFence
+
+	switch left := len(t.Rows) - n; {
+	case left == 0 && !t.More:
+	case t.NextPage != nil:
+		page += "\n" + t.NextPage(n)
+	case t.More:
+		page += "\nMore rows not shown."
+	default:
+		page += fmt.Sprintf("\n%d more rows not shown.", left)
+	}
+	return page
+}
@@ -0,0 +1,129 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// content drops the whitespace and fences SplitMessage may add or move.
+func content(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, codeFence, "")), "")
+}
+
+func TestSplitMessage(t *testing.T) {
+	block := func(lines int) string {
+		var b strings.Builder
+		b.WriteString("```\n")
+		for i := range lines {
+			fmt.Fprintf(&b, "row %02d\n", i)
+		}
+		b.WriteString("```")
+		return b.String()
+	}
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		// parts is the number of messages expected, 0 to skip the check
+		parts int
+	}{
+		{"fits", "hello\nworld", 20, 1},
+		{"lines", "aaaaaaaaaa\nbbbbbbbbbb\ncccccccccc\n", 25, 2},
+		{"line longer than the limit", strings.Repeat("a", 50), 20, 0},
+		{"multibyte runes at the cut", strings.Repeat("é", 30) + "\n" + strings.Repeat("日本", 20), 21, 0},
+		{"block spanning messages", block(20), 40, 0},
+		{"blocks inside one message", "a\n```\nx\n```\nb\n```\ny\n```\nc\n" + strings.Repeat("d\n", 20), 30, 0},
+		{"block opened and closed on one line", strings.Repeat("say ```hi``` now\n", 10), 40, 0},
+		{"long line inside a block", "```\n" + strings.Repeat("x", 100) + "\n```", 30, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := SplitMessage(tt.text, tt.limit)
+			if tt.parts != 0 && len(messages) != tt.parts {
+				t.Errorf("%d messages, want %d: %q", len(messages), tt.parts, messages)
+			}
+			for _, m := range messages {
+				if len(m) > tt.limit {
+					t.Errorf("message of %d bytes over the limit %d: %q", len(m), tt.limit, m)
+				}
+				if !utf8.ValidString(m) {
+					t.Errorf("message cuts a rune: %q", m)
+				}
+				if strings.Count(m, codeFence)%2 != 0 {
+					t.Errorf("message leaves a code block open: %q", m)
+				}
+				if strings.TrimSpace(m) == "" {
+					t.Error("empty message")
+				}
+			}
+			if got, want := content(strings.Join(messages, "")), content(tt.text); got != want {
+				t.Errorf("content changed:\n got %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSplitMessageReopensBlocks(t *testing.T) {
+	text := "Top donors:\n```\n" + strings.Repeat("alice  500\n", 10) + "```\nThanks!"
+	messages := SplitMessage(text, 60)
+	if len(messages) < 3 {
+		t.Fatalf("%d messages, want the block split: %q", len(messages), messages)
+	}
+	for _, m := range messages[1 : len(messages)-1] {
+		if !strings.HasPrefix(m, codeFence) || !strings.HasSuffix(m, codeFence) {
+			t.Errorf("message inside the block is not a block: %q", m)
+		}
+	}
+}
+
+func TestTableRender(t *testing.T) {
+	rows := func(n int) []string {
+		var rows []string
+		for i := range n {
+			rows = append(rows, fmt.Sprintf("donor%02d\t%d", i, i*100))
+		}
+		return rows
+	}
+	tests := []struct {
+		name  string
+		table Table
+		// shown is the number of rows expected, footer the end of the output
+		shown  int
+		footer string
+	}{
+		{"no rows", Table{Header: "Donor\tTotal"}, 0, codeFence},
+		{"all rows fit", Table{Header: "Donor\tTotal", Rows: rows(3)}, 3, codeFence},
+		{"more after a query limit", Table{Header: "Donor\tTotal", Rows: rows(3), More: true}, 3, "\nMore rows not shown."},
+		{"rows left out", Table{Header: "Donor\tTotal", Rows: rows(50), Limit: 200}, 11, "\n39 more rows not shown."},
+		{"next page", Table{Header: "Donor\tTotal", Rows: rows(50), Limit: 200, NextPage: func(next int) string {
+			return fmt.Sprintf("Next page: -page %d", next)
+		}}, 12, "\nNext page: -page 12"},
+		{"single row over the limit", Table{Header: "Donor\tTotal", Rows: []string{strings.Repeat("x", 300) + "\t1", "y\t2"}, Limit: 100}, 1, "\n1 more rows not shown."},
+		{"default limit", Table{Header: "Donor\tTotal", Rows: rows(500)}, 0, " more rows not shown."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := tt.table.Render()
+			limit := tt.table.Limit
+			if limit == 0 {
+				limit = MessageLimit
+			}
+			if len(out) > limit && tt.shown != 1 {
+				t.Errorf("%d bytes over the limit %d", len(out), limit)
+			}
+			if !strings.HasPrefix(out, codeFence+"Donor") || !strings.HasSuffix(out, tt.footer) {
+				t.Errorf("output does not end with %q:\n%s", tt.footer, out)
+			}
+			shown := strings.Count(out, "\n") - 1 - strings.Count(tt.footer, "\n")
+			if tt.shown != 0 && shown != tt.shown {
+				t.Errorf("%d rows shown, want %d:\n%s", shown, tt.shown, out)
+			}
+			// one more row would not have fit
+			if shown < len(tt.table.Rows) && shown > 1 && len(tt.table.page(shown+1)) <= limit {
+				t.Errorf("%d rows shown, but %d fit", shown, shown+1)
+			}
+		})
+	}
+}
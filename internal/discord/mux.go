@@ -0,0 +1,343 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Mux routes command lines to a tree of commands, it is shared by the
+// backends. A command is named by one or more words, "goal create" is the
+// subcommand create of goal.
+type Mux struct {
+	root  node
+	guard Guard
+}
+
+type node struct {
+	// path is the full name of the node, e.g. "goal create".
+	path    string
+	command Command
+	handler HandlerFunc
+	// children are keyed by their names and by the aliases ending below
+	// this node.
+	children map[string]*node
+}
+
+// SetGuard makes every command check the level of its caller and end up in
+// the audit log. Without a guard every caller may run everything.
+func (m *Mux) SetGuard(guard Guard) {
+	m.guard = guard
+}
+
+// AddCommand adds command to the tree, run by handler. Missing parents are
+// added as groups. A command without handler is a group, it only prints the
+// help of its subcommands.
+func (m *Mux) AddCommand(command Command, handler HandlerFunc) {
+	n := m.root.add(strings.Fields(strings.ToLower(command.Name)))
+	command.Name = n.path
+	n.command = command
+	n.handler = handler
+	for _, alias := range command.Aliases {
+		words := strings.Fields(strings.ToLower(alias))
+		if len(words) == 0 {
+			continue
+		}
+		parent := m.root.add(words[:len(words)-1])
+		parent.children[words[len(words)-1]] = n
+	}
+}
+
+// add returns the node at path, creating the missing ones.
+func (n *node) add(path []string) *node {
+	for _, word := range path {
+		if n.children == nil {
+			n.children = make(map[string]*node)
+		}
+		child, ok := n.children[word]
+		if !ok {
+			child = &node{path: strings.TrimSpace(n.path + " " + word)}
+			child.command.Name = child.path
+			n.children[word] = child
+		}
+		n = child
+	}
+	if n.children == nil {
+		n.children = make(map[string]*node)
+	}
+	return n
+}
+
+// walk follows args down the tree while they name subcommands, it returns
+// the node reached and the args left.
+func (n *node) walk(args []string) (*node, []string) {
+	for len(args) > 0 {
+		child, ok := n.children[strings.ToLower(args[0])]
+		if !ok {
+			break
+		}
+		n, args = child, args[1:]
+	}
+	return n, args
+}
+
+// subcommands returns the children of n without aliases, sorted.
+func (n *node) subcommands() []*node {
+	var nodes []*node
+	for word, child := range n.children {
+		if child.path == strings.TrimSpace(n.path+" "+word) {
+			nodes = append(nodes, child)
+		}
+	}
+	slices.SortFunc(nodes, func(a, b *node) int {
+		return strings.Compare(a.path, b.path)
+	})
+	return nodes
+}
+
+// runnable returns n and every command below it that has a handler, sorted.
+func (n *node) runnable() []*node {
+	var nodes []*node
+	if n.handler != nil {
+		nodes = append(nodes, n)
+	}
+	for _, child := range n.subcommands() {
+		nodes = append(nodes, child.runnable()...)
+	}
+	return nodes
+}
+
+// Commands returns the names of the top-level commands and aliases, sorted.
+func (m *Mux) Commands() []string {
+	commands := make([]string, 0, len(m.root.children))
+	for command := range m.root.children {
+		commands = append(commands, command)
+	}
+	slices.Sort(commands)
+	return commands
+}
+
+// Summary returns the summary of a top-level command or alias, empty when
+// there is no such command.
+func (m *Mux) Summary(command string) string {
+	if n, ok := m.root.children[strings.ToLower(command)]; ok {
+		return n.command.Summary
+	}
+	return ""
+}
+
+// required returns the highest permission on the path of n, the permission
+// of a group covers its subcommands even when they are run by an alias.
+func (m *Mux) required(n *node) Level {
+	level := LevelEveryone
+	current := &m.root
+	for _, word := range strings.Fields(n.path) {
+		current = current.children[word]
+		level = max(level, current.command.Permission)
+	}
+	return level
+}
+
+// Dispatch runs the command named by the first args for caller, writing its
+// output to w. "help [command]" and groups print the help, unknown commands
+// get suggestions.
+func (m *Mux) Dispatch(caller Caller, args []string, raw string, w io.Writer) {
+	if len(args) == 0 {
+		args = []string{"help"}
+	}
+	if strings.EqualFold(args[0], "help") {
+		if _, ok := m.authorize(caller, "help", args[1:], raw, LevelEveryone, w); ok {
+			m.writeHelp(args[1:], w)
+		}
+		return
+	}
+
+	n, rest := m.root.walk(args)
+	switch {
+	case n == &m.root:
+		m.writeUnknown(n, args[0], w)
+	case n.handler == nil && len(rest) > 0 && !strings.HasPrefix(rest[0], "-"):
+		m.writeUnknown(n, rest[0], w)
+	case n.handler == nil:
+		fmt.Fprintln(w, m.help(n))
+	default:
+		if messageArgs, ok := m.authorize(caller, n.path, rest, raw, m.required(n), w); ok {
+			n.handler(messageArgs, w)
+		}
+	}
+}
+
+// authorize checks that caller has the required level and audits the
+// command.
+func (m *Mux) authorize(caller Caller, command string, args []string, raw string, required Level, w io.Writer) (DiscordMessageArgs, bool) {
+	messageArgs := DiscordMessageArgs{
+		Raw:         raw,
+		Args:        args,
+		CommandName: command,
+		Caller:      caller,
+		Level:       LevelAdmin,
+	}
+	if m.guard != nil {
+		ctx := context.Background()
+		level, err := m.guard.Level(ctx, caller)
+		if err != nil {
+			fmt.Fprintf(w, "Error checking permissions: %v\n", err)
+			return messageArgs, false
+		}
+		messageArgs.Level = level
+		m.guard.Audit(ctx, messageArgs, required, level >= required)
+	}
+	if messageArgs.Level < required {
+		fmt.Fprintf(w, "Permission denied: %s needs the %s level, you have %s.\n", command, required, messageArgs.Level)
+		return messageArgs, false
+	}
+	return messageArgs, true
+}
+
+// Execute runs a command line for caller starting at the command name, e.g.
+// "donation top -by count". The line is split by SplitArgs, only command
+// names are case-insensitive. An empty line prints the help.
+func (m *Mux) Execute(caller Caller, line string, w io.Writer) {
+	line = strings.TrimSpace(line)
+	args, err := SplitArgs(line)
+	if err != nil {
+		fmt.Fprintf(w, "Invalid command: %v\n", err)
+		return
+	}
+	m.Dispatch(caller, args, line, w)
+}
+
+func (m *Mux) writeHelp(args []string, w io.Writer) {
+	if len(args) == 0 {
+		fmt.Fprint(w, m.GeneretaHelp())
+		return
+	}
+	n, rest := m.root.walk(args)
+	switch {
+	case n == &m.root:
+		m.writeUnknown(n, args[0], w)
+	case len(rest) > 0:
+		m.writeUnknown(n, rest[0], w)
+	default:
+		fmt.Fprintln(w, m.help(n))
+	}
+}
+
+// help returns the usage and flags of n and of the commands below it.
+func (m *Mux) help(n *node) string {
+	nodes := n.runnable()
+	if len(nodes) == 1 && nodes[0] == n {
+		return n.command.Help()
+	}
+	commands := make([]Command, 0, len(nodes))
+	for _, c := range nodes {
+		commands = append(commands, c.command)
+	}
+	summary := n.command.Summary
+	if n.handler != nil {
+		// the summary of n is the heading already
+		commands[0].Summary = ""
+	}
+	if summary == "" {
+		summary = "Subcommands of " + n.path + ":"
+	}
+	return Help(summary, commands...)
+}
+
+// GeneretaHelp lists every command with its summary, sorted.
+func (m *Mux) GeneretaHelp() string {
+	nodes := m.root.runnable()
+	if len(nodes) == 0 {
+		return "No commands available."
+	}
+	var helpMessage strings.Builder
+	helpMessage.WriteString("Commands:\n")
+	for _, n := range nodes {
+		helpMessage.WriteString("  " + n.path)
+		if n.command.Summary != "" {
+			helpMessage.WriteString(" - " + n.command.Summary)
+		}
+		if len(n.command.Aliases) > 0 {
+			helpMessage.WriteString(" (alias " + strings.Join(n.command.Aliases, ", ") + ")")
+		}
+		helpMessage.WriteString("\n")
+	}
+	helpMessage.WriteString("Use help <command> to see its flags.\n")
+	return helpMessage.String()
+}
+
+// writeUnknown reports that word is not a subcommand of n, suggesting the
+// closest ones.
+func (m *Mux) writeUnknown(n *node, word string, w io.Writer) {
+	if n == &m.root {
+		fmt.Fprintf(w, "Unknown command: %s\n", word)
+	} else {
+		fmt.Fprintf(w, "Unknown %s command: %s\n", n.path, word)
+	}
+	if suggestions := suggest(n, word); len(suggestions) > 0 {
+		fmt.Fprintf(w, "Did you mean %s?\n", strings.Join(suggestions, " or "))
+	}
+	if n == &m.root {
+		fmt.Fprintln(w, "Use help to list the commands.")
+	} else {
+		fmt.Fprintf(w, "Use help %s to list its commands.\n", n.path)
+	}
+}
+
+// maxSuggestions bounds the commands suggested for an unknown one.
+const maxSuggestions = 3
+
+// suggest returns the children of n that word is a prefix or a typo of,
+// closest first.
+func suggest(n *node, word string) []string {
+	word = strings.ToLower(word)
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for key := range n.children {
+		distance := levenshtein(word, key)
+		if strings.HasPrefix(key, word) || distance <= max(2, len(key)/3) && distance < len(key) {
+			candidates = append(candidates, candidate{
+				name:     strings.TrimSpace(n.path + " " + key),
+				distance: distance,
+			})
+		}
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	names := make([]string, 0, maxSuggestions)
+	for _, c := range candidates[:min(len(candidates), maxSuggestions)] {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// levenshtein returns the number of single letter edits turning a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := range ra {
+		current[0] = i + 1
+		for j := range rb {
+			cost := 1
+			if ra[i] == rb[j] {
+				cost = 0
+			}
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,54 @@
+package discord
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Level is what a caller may run, every level includes the ones below it.
+type Level int
+
+const (
+	LevelEveryone Level = iota
+	LevelModerator
+	LevelAdmin
+)
+
+// String returns the name of l, see config.PermissionLevels.
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(config.PermissionLevels) {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return config.PermissionLevels[l]
+}
+
+func ParseLevel(s string) (Level, error) {
+	if i := slices.Index(config.PermissionLevels, s); i >= 0 {
+		return Level(i), nil
+	}
+	return 0, fmt.Errorf("unknown level %q, use everyone, moderator or admin", s)
+}
+
+// Caller is who ran a command: the Discord user, the channel it was sent in
+// and the roles of the user there. The legacy bridge protocol doesn't tell,
+// its callers are anonymous.
+type Caller struct {
+	UserID    string
+	ChannelID string
+	RoleIDs   []string
+}
+
+func (c Caller) Anonymous() bool {
+	return c.UserID == ""
+}
+
+// Guard decides what callers may run and keeps the audit log, see
+// Mux.SetGuard.
+type Guard interface {
+	// Level returns the level of caller.
+	Level(ctx context.Context, caller Caller) (Level, error)
+	// Audit records a command, allowed tells whether it ran.
+	Audit(ctx context.Context, args DiscordMessageArgs, required Level, allowed bool)
+}
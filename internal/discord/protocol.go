@@ -0,0 +1,145 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// The bridge speaks one of two protocols with the relay, chosen by
+// discord.protocol.
+//
+// legacy: after connecting the bridge sends "SET_NAME:<name>" and
+// "GET_DELIMITER:" lines, the relay answers "DELIMITER:<delimiter>". Every
+// other line from the relay is a command, everything the bridge sends is
+// text terminated by a line with the delimiter. Replies and alerts can't be
+// told apart.
+//
+// v1: every frame is a JSON Message on its own line, newlines inside the text
+// are escaped by JSON. The bridge starts with a hello carrying the protocol
+// version, its name and capabilities, the relay answers with its own hello and
+// only capabilities both sides listed are used. Then the relay sends
+// "command" messages with an id and the command line starting at the command
+// name, and the Discord user, channel and roles of whoever sent it so the
+// bridge can check permissions; the bridge answers each with one "reply" of
+// the same id, or several with "more" set on all but the last when the relay
+// takes replies in parts. Commands without a user are anonymous. "alert"
+// messages are sent on their own and carry no id. Either side answers a
+// frame it can't handle with an "error" that has the id of that frame when
+// it had one.
+const ProtocolVersion = 1
+
+// Message types of the v1 protocol.
+const (
+	TypeHello   = "hello"
+	TypeCommand = "command"
+	TypeReply   = "reply"
+	TypeAlert   = "alert"
+	TypeError   = "error"
+)
+
+const (
+	// CapabilityAlertChannel means the relay routes alerts by their channel,
+	// the Twitch channel the alert is about. Without it alerts have no
+	// channel.
+	CapabilityAlertChannel = "alert-channel"
+	// CapabilityReplyParts means the relay takes a reply in parts that fit
+	// into a Discord message. Every part but the last has More set. Without
+	// it a reply is sent whole and the relay splits it.
+	CapabilityReplyParts = "reply-parts"
+)
+
+// capabilities are what this side of the v1 protocol supports.
+var capabilities = []string{CapabilityAlertChannel, CapabilityReplyParts}
+
+// Message is a frame of the v1 protocol.
+type Message struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	Text string `json:"text,omitempty"`
+	// Channel is the Twitch channel an alert is about.
+	Channel string `json:"channel,omitempty"`
+	// More tells that more parts of a reply follow, see
+	// CapabilityReplyParts.
+	More bool `json:"more,omitempty"`
+	// UserID, ChannelID and RoleIDs are the caller of a command.
+	UserID    string   `json:"user_id,omitempty"`
+	ChannelID string   `json:"channel_id,omitempty"`
+	RoleIDs   []string `json:"role_ids,omitempty"`
+	// Version, Name and Capabilities are only sent with hello.
+	Version      int      `json:"version,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// encodeFrame returns m as a v1 frame.
+func encodeFrame(m Message) []byte {
+	frame, _ := json.Marshal(m)
+	return append(frame, '\n')
+}
+
+// handleFrame handles a v1 frame from the relay.
+func (w *Server) handleFrame(ctx context.Context, line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	var m Message
+	if err := json.Unmarshal(line, &m); err != nil {
+		w.send(ctx, Message{Type: TypeError, Text: "invalid frame: " + err.Error()})
+		return
+	}
+
+	switch m.Type {
+	case TypeHello:
+		if m.Version != ProtocolVersion {
+			w.send(ctx, Message{Type: TypeError, Text: fmt.Sprintf("unsupported protocol version %d, want %d", m.Version, ProtocolVersion)})
+			return
+		}
+		var negotiated []string
+		for _, c := range m.Capabilities {
+			if slices.Contains(capabilities, c) {
+				negotiated = append(negotiated, c)
+			}
+		}
+		w.mu.Lock()
+		w.capabilities = negotiated
+		w.mu.Unlock()
+	case TypeCommand:
+		if w.onMessage != nil {
+			w.onMessage(m.Text, w)
+		}
+		buf := &bytes.Buffer{}
+		w.Execute(Caller{UserID: m.UserID, ChannelID: m.ChannelID, RoleIDs: m.RoleIDs}, m.Text, buf)
+		w.reply(ctx, m.ID, strings.TrimSpace(buf.String()))
+	case TypeError:
+		slog.Warn("Discord relay reported an error", "id", m.ID, "error", m.Text)
+	default:
+		w.send(ctx, Message{Type: TypeError, ID: m.ID, Text: "unknown message type: " + m.Type})
+	}
+}
+
+// reply sends the reply to the command id, in parts when the relay takes
+// them.
+func (w *Server) reply(ctx context.Context, id string, text string) {
+	if !w.hasCapability(CapabilityReplyParts) {
+		w.send(ctx, Message{Type: TypeReply, ID: id, Text: text})
+		return
+	}
+	parts := SplitMessage(text, MessageLimit)
+	for i, part := range parts {
+		if err := w.send(ctx, Message{Type: TypeReply, ID: id, Text: part, More: i < len(parts)-1}); err != nil {
+			return
+		}
+	}
+}
+
+func (w *Server) hasCapability(capability string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Contains(w.capabilities, capability)
+}
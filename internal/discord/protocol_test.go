@@ -0,0 +1,161 @@
+package discord
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// startProtocolServer runs a Server with the commands "echo", replying with
+// its arguments and caller, and "long", replying with more than a Discord
+// message.
+func startProtocolServer(t *testing.T, protocol string) (*Server, *relayConn) {
+	t.Helper()
+	relay := newFakeRelay(t)
+	s := startServer(t, relay, protocol)
+	s.AddCommand(Command{Name: "echo"}, func(args DiscordMessageArgs, w io.Writer) {
+		fmt.Fprintf(w, "%s from %q in %q", strings.Join(args.Args, " "), args.Caller.UserID, args.Caller.ChannelID)
+	})
+	s.AddCommand(Command{Name: "long"}, func(args DiscordMessageArgs, w io.Writer) {
+		for i := range 100 {
+			fmt.Fprintf(w, "line %d %s\n", i, strings.Repeat("x", 40))
+		}
+	})
+	return s, relay.accept(t)
+}
+
+// helloV1 reads the hello of the server and answers with capabilities.
+func helloV1(t *testing.T, conn *relayConn, capabilities ...string) {
+	t.Helper()
+	m := conn.readFrame(t)
+	if m.Type != TypeHello || m.Version != ProtocolVersion || m.Name != "TwitchDonoCalculator" {
+		t.Fatalf("hello %+v", m)
+	}
+	if !slices.Equal(m.Capabilities, []string{CapabilityAlertChannel, CapabilityReplyParts}) {
+		t.Errorf("capabilities %v", m.Capabilities)
+	}
+	frame := string(encodeFrame(Message{Type: TypeHello, Version: ProtocolVersion, Capabilities: capabilities}))
+	conn.send(t, strings.TrimSpace(frame))
+}
+
+func sendFrame(t *testing.T, conn *relayConn, m Message) {
+	t.Helper()
+	conn.send(t, strings.TrimSpace(string(encodeFrame(m))))
+}
+
+func TestProtocolV1Command(t *testing.T) {
+	_, conn := startProtocolServer(t, config.BridgeProtocolV1)
+	helloV1(t, conn)
+
+	sendFrame(t, conn, Message{Type: TypeCommand, ID: "42", Text: "echo hello there", UserID: "1", ChannelID: "2"})
+	m := conn.readFrame(t)
+	if m.Type != TypeReply || m.ID != "42" || m.More {
+		t.Fatalf("reply %+v", m)
+	}
+	if want := `hello there from "1" in "2"`; m.Text != want {
+		t.Errorf("reply text %q, want %q", m.Text, want)
+	}
+}
+
+func TestProtocolV1Capabilities(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities []string
+		wantChannel  string
+		wantParts    bool
+	}{
+		{"none", nil, "", false},
+		{"alert channel", []string{CapabilityAlertChannel, "unknown"}, "#tartancz", false},
+		{"reply parts", []string{CapabilityReplyParts}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, conn := startProtocolServer(t, config.BridgeProtocolV1)
+			helloV1(t, conn, tt.capabilities...)
+
+			sendFrame(t, conn, Message{Type: TypeCommand, ID: "7", Text: "long"})
+			var parts []Message
+			for {
+				m := conn.readFrame(t)
+				if m.Type != TypeReply || m.ID != "7" {
+					t.Fatalf("frame %+v, want a reply to 7", m)
+				}
+				parts = append(parts, m)
+				if !m.More {
+					break
+				}
+			}
+			if tt.wantParts {
+				if len(parts) < 2 {
+					t.Errorf("reply in %d parts, want several", len(parts))
+				}
+				for _, p := range parts {
+					if len(p.Text) > MessageLimit {
+						t.Errorf("part of %d characters", len(p.Text))
+					}
+				}
+			} else if len(parts) != 1 || len(parts[0].Text) <= MessageLimit {
+				t.Errorf("reply in %d parts, want one whole reply", len(parts))
+			}
+
+			// the reply came after the hello was handled
+			fmt.Fprint(s.Alerts("#tartancz"), "alert")
+			if m := conn.readFrame(t); m.Type != TypeAlert || m.Text != "alert" || m.Channel != tt.wantChannel || m.ID != "" {
+				t.Errorf("alert %+v, want channel %q", m, tt.wantChannel)
+			}
+		})
+	}
+}
+
+func TestProtocolV1Errors(t *testing.T) {
+	_, conn := startProtocolServer(t, config.BridgeProtocolV1)
+	helloV1(t, conn)
+
+	sendFrame(t, conn, Message{Type: "subscribe", ID: "9"})
+	if m := conn.readFrame(t); m.Type != TypeError || m.ID != "9" || !strings.Contains(m.Text, "unknown message type: subscribe") {
+		t.Errorf("unknown type answered with %+v", m)
+	}
+	sendFrame(t, conn, Message{Type: TypeHello, Version: 2})
+	if m := conn.readFrame(t); m.Type != TypeError || !strings.Contains(m.Text, "unsupported protocol version 2") {
+		t.Errorf("unknown version answered with %+v", m)
+	}
+	conn.send(t, "not json")
+	if m := conn.readFrame(t); m.Type != TypeError || !strings.HasPrefix(m.Text, "invalid frame") {
+		t.Errorf("invalid frame answered with %+v", m)
+	}
+	// errors from the relay are only logged
+	sendFrame(t, conn, Message{Type: TypeError, Text: "oops"})
+	sendFrame(t, conn, Message{Type: TypeCommand, ID: "10", Text: "echo still here"})
+	if m := conn.readFrame(t); m.Type != TypeReply || m.ID != "10" {
+		t.Errorf("frame %+v, want the reply to 10", m)
+	}
+}
+
+func TestProtocolLegacy(t *testing.T) {
+	s, conn := startProtocolServer(t, config.BridgeProtocolLegacy)
+	if line := conn.readLine(t); line != "SET_NAME:TwitchDonoCalculator" {
+		t.Fatalf("first line %q", line)
+	}
+	if line := conn.readLine(t); line != "GET_DELIMITER:" {
+		t.Fatalf("second line %q", line)
+	}
+	conn.send(t, "DELIMITER:---END---")
+
+	conn.send(t, "!bot TwitchDonoCalculator echo hello")
+	if line := conn.readLine(t); line != `hello from "" in ""` {
+		t.Errorf("reply %q", line)
+	}
+	if line := conn.readLine(t); line != "---END---" {
+		t.Errorf("reply ends with %q, want the delimiter", line)
+	}
+
+	fmt.Fprint(s.Alerts("#tartancz"), "alert\nwith two lines")
+	for _, want := range []string{"alert", "with two lines", "---END---"} {
+		if line := conn.readLine(t); line != want {
+			t.Errorf("alert line %q, want %q", line, want)
+		}
+	}
+}
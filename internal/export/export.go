@@ -0,0 +1,214 @@
+package export
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type Format string
+
+const (
+	CSV     Format = "csv"
+	NDJSON  Format = "ndjson"
+	Parquet Format = "parquet"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case CSV, NDJSON, Parquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, use csv, ndjson or parquet", s)
+}
+
+// Extension returns the file extension for f, without the dot.
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Binary reports whether f can't be shown as text.
+func (f Format) Binary() bool {
+	return f == Parquet
+}
+
+// Row is one exported donation.
+type Row struct {
+	ID        int64     `json:"id" parquet:"id"`
+	Timestamp time.Time `json:"timestamp" parquet:"timestamp"`
+	Channel   string    `json:"channel" parquet:"channel"`
+	Donor     string    `json:"donor" parquet:"donor"`
+	Amount    int64     `json:"amount" parquet:"amount"`
+	Text      string    `json:"text" parquet:"text"`
+	User      string    `json:"user" parquet:"user"`
+	SessionID *int64    `json:"sessionId" parquet:"session_id,optional"`
+	Source    string    `json:"source" parquet:"source"`
+}
+
+var csvHeader = []string{"id", "timestamp", "channel", "donor", "amount", "text", "user", "session_id", "source"}
+
+func NewRow(d db.Donation) Row {
+	r := Row{
+		ID:        d.ID,
+		Timestamp: d.Timestamp.UTC(),
+		Channel:   d.Channel,
+		Donor:     d.SendFrom,
+		Amount:    d.Amount,
+		Text:      d.Text,
+		User:      d.User,
+		Source:    d.Source,
+	}
+	if d.SessionID.Valid {
+		r.SessionID = &d.SessionID.Int64
+	}
+	return r
+}
+
+// Writer encodes rows in one format. Close flushes buffered rows and must be
+// called once all rows are written, it does not close the underlying writer.
+type Writer interface {
+	Write(rows []Row) error
+	Close() error
+}
+
+func NewWriter(format Format, w io.Writer) Writer {
+	switch format {
+	case NDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}
+	case Parquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[Row](w)}
+	default:
+		return &csvWriter{w: csv.NewWriter(w)}
+	}
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvWriter) Write(rows []Row) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, r := range rows {
+		sessionID := ""
+		if r.SessionID != nil {
+			sessionID = strconv.FormatInt(*r.SessionID, 10)
+		}
+		record := []string{
+			strconv.FormatInt(r.ID, 10),
+			r.Timestamp.Format(time.RFC3339),
+			r.Channel,
+			r.Donor,
+			strconv.FormatInt(r.Amount, 10),
+			r.Text,
+			r.User,
+			sessionID,
+			r.Source,
+		}
+		if err := c.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if !c.headerWritten {
+		if err := c.Write(nil); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(rows []Row) error {
+	for _, r := range rows {
+		if err := n.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+type parquetWriter struct {
+	w *parquet.GenericWriter[Row]
+}
+
+func (p *parquetWriter) Write(rows []Row) error {
+	_, err := p.w.Write(rows)
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
+
+// Filter selects the exported donations. The range is half-open [From, To),
+// empty Channel and Donor match everything.
+type Filter struct {
+	Channel string
+	Donor   string
+	From    time.Time
+	To      time.Time
+}
+
+// batchSize is how many donations are read from the database at once, so
+// large ranges are never held in memory.
+const batchSize = 1000
+
+// Donations writes every donation matching filter to w in id order and
+// returns how many were written. It does not close w.
+func Donations(ctx context.Context, q *db.Queries, filter Filter, w Writer) (int, error) {
+	var afterID int64
+	written := 0
+	for {
+		donations, err := q.ListDonationsForExport(ctx, db.ListDonationsForExportParams{
+			Channel:       filter.Channel,
+			Donor:         filter.Donor,
+			FromTimestamp: filter.From,
+			ToTimestamp:   filter.To,
+			AfterID:       afterID,
+			RowLimit:      batchSize,
+		})
+		if err != nil {
+			return written, err
+		}
+		if len(donations) == 0 {
+			return written, nil
+		}
+
+		rows := make([]Row, len(donations))
+		for i, d := range donations {
+			rows[i] = NewRow(d)
+		}
+		if err := w.Write(rows); err != nil {
+			return written, err
+		}
+		written += len(rows)
+		afterID = donations[len(donations)-1].ID
+		if len(donations) < batchSize {
+			return written, nil
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package feed
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TypeDonation events are kept for replay, events of other types are only
+// sent to current subscribers.
+const TypeDonation = "donation"
+
+type Event struct {
+	Type string
+	// ID orders donation events, it is the donation id.
+	ID   int64
+	Data any
+}
+
+// LoadFunc returns up to n of the most recent donation events of channel,
+// oldest first. It primes the replay buffer after a restart.
+type LoadFunc func(ctx context.Context, channel string, n int) ([]Event, error)
+
+// subscriberBuffer is how many events a subscriber may lag behind before it
+// is disconnected.
+const subscriberBuffer = 32
+
+// loadTimeout bounds priming the replay buffer of a channel.
+const loadTimeout = 10 * time.Second
+
+// Hub fans out events per channel and keeps the last events of every channel
+// for subscribers that connect later. A channel is only kept while it has
+// subscribers.
+type Hub struct {
+	size int
+	load LoadFunc
+
+	mu       sync.Mutex
+	channels map[string]*channelFeed
+}
+
+type channelFeed struct {
+	// users counts the subscribers, including the ones waiting for the
+	// load, the feed is dropped when it reaches zero.
+	users       int
+	loadOnce    sync.Once
+	loadErr     error
+	replay      []Event
+	subscribers map[chan Event]struct{}
+}
+
+// NewHub keeps the last size donation events per channel. load may be nil.
+func NewHub(size int, load LoadFunc) *Hub {
+	return &Hub{
+		size:     size,
+		load:     load,
+		channels: make(map[string]*channelFeed),
+	}
+}
+
+// acquire returns the feed of channel, creating it, and counts a user of it.
+func (h *Hub) acquire(channel string) *channelFeed {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, ok := h.channels[channel]
+	if !ok {
+		f = &channelFeed{subscribers: make(map[chan Event]struct{})}
+		h.channels[channel] = f
+	}
+	f.users++
+	return f
+}
+
+// release undoes acquire, h.mu must be held.
+func (h *Hub) release(channel string, f *channelFeed) {
+	f.users--
+	if f.users == 0 && h.channels[channel] == f {
+		delete(h.channels, channel)
+	}
+}
+
+// prime loads the replay buffer of f the first time it is called. The load
+// runs without h.mu, so Publish never waits for the database. Donations
+// published meanwhile are kept after the loaded ones.
+func (h *Hub) prime(ctx context.Context, channel string, f *channelFeed) error {
+	f.loadOnce.Do(func() {
+		if h.load == nil || h.size <= 0 {
+			return
+		}
+		// shared by every subscriber waiting for it, so not cancelled with
+		// the first one
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		events, err := h.load(ctx, channel, h.size)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if err != nil {
+			f.loadErr = err
+			// the next subscriber tries again with a new feed
+			if h.channels[channel] == f {
+				delete(h.channels, channel)
+			}
+			return
+		}
+		var lastID int64
+		if len(events) > 0 {
+			lastID = events[len(events)-1].ID
+		}
+		for _, e := range f.replay {
+			if e.ID > lastID {
+				events = append(events, e)
+			}
+		}
+		f.replay = events[len(events)-min(len(events), h.size):]
+	})
+	return f.loadErr
+}
+
+// Publish sends e to every subscriber of channel. Subscribers that can't keep
+// up are disconnected by closing their channel.
+func (h *Hub) Publish(channel string, e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, ok := h.channels[channel]
+	if !ok {
+		// nobody subscribed, the next subscriber loads the history
+		return
+	}
+	if e.Type == TypeDonation && h.size > 0 {
+		f.replay = append(f.replay, e)
+		if len(f.replay) > h.size {
+			f.replay = f.replay[len(f.replay)-h.size:]
+		}
+	}
+
+	for sub := range f.subscribers {
+		select {
+		case sub <- e:
+		default:
+			delete(f.subscribers, sub)
+			close(sub)
+		}
+	}
+}
+
+// Subscribe returns the kept donation events of channel newer than lastID,
+// oldest first, and a channel of future events. The channel is
+// closed when the subscriber falls behind; cancel must be called when done.
+func (h *Hub) Subscribe(ctx context.Context, channel string, lastID int64) ([]Event, <-chan Event, func(), error) {
+	f := h.acquire(channel)
+	if err := h.prime(ctx, channel, f); err != nil {
+		h.mu.Lock()
+		h.release(channel, f)
+		h.mu.Unlock()
+		return nil, nil, nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var replay []Event
+	for _, e := range f.replay {
+		if e.ID > lastID {
+			replay = append(replay, e)
+		}
+	}
+
+	sub := make(chan Event, subscriberBuffer)
+	f.subscribers[sub] = struct{}{}
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := f.subscribers[sub]; ok {
+				delete(f.subscribers, sub)
+				close(sub)
+			}
+			h.release(channel, f)
+		})
+	}
+	return replay, sub, cancel, nil
+}
@@ -0,0 +1,207 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const channel = "#tartancz"
+
+func donation(id int64) Event {
+	return Event{Type: TypeDonation, ID: id, Data: id}
+}
+
+// staticLoad loads the donations with the given ids.
+func staticLoad(ids ...int64) LoadFunc {
+	return func(_ context.Context, _ string, n int) ([]Event, error) {
+		var events []Event
+		for _, id := range ids[len(ids)-min(len(ids), n):] {
+			events = append(events, donation(id))
+		}
+		return events, nil
+	}
+}
+
+func ids(events []Event) []int64 {
+	var ids []int64
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func subscribe(t *testing.T, h *Hub, lastID int64) ([]Event, <-chan Event, func()) {
+	t.Helper()
+	replay, events, cancel, err := h.Subscribe(context.Background(), channel, lastID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cancel)
+	return replay, events, cancel
+}
+
+func TestReplay(t *testing.T) {
+	h := NewHub(3, staticLoad(1, 2, 3, 4))
+	replay, events, _ := subscribe(t, h, 0)
+	if got := ids(replay); !slices.Equal(got, []int64{2, 3, 4}) {
+		t.Fatalf("replay %v, want the last 3 loaded", got)
+	}
+
+	h.Publish(channel, donation(5))
+	h.Publish(channel, Event{Type: "state"})
+	if e := <-events; e.ID != 5 {
+		t.Errorf("got %+v, want donation 5", e)
+	}
+	if e := <-events; e.Type != "state" {
+		t.Errorf("got %+v, want the state", e)
+	}
+
+	// only donations are kept, the buffer keeps its size
+	replay, _, _ = subscribe(t, h, 0)
+	if got := ids(replay); !slices.Equal(got, []int64{3, 4, 5}) {
+		t.Errorf("replay %v, want 3 4 5", got)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	h := NewHub(10, staticLoad(1, 2, 3))
+	subscribe(t, h, 0)
+	h.Publish(channel, donation(4))
+
+	tests := map[int64][]int64{
+		0: {1, 2, 3, 4},
+		2: {3, 4},
+		4: nil,
+		9: nil,
+	}
+	for lastID, want := range tests {
+		replay, _, _ := subscribe(t, h, lastID)
+		if got := ids(replay); !slices.Equal(got, want) {
+			t.Errorf("Last-Event-ID %d: replay %v, want %v", lastID, got, want)
+		}
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	h := NewHub(5, nil)
+	_, slow, _ := subscribe(t, h, 0)
+	_, fast, _ := subscribe(t, h, 0)
+
+	for i := range int64(subscriberBuffer + 1) {
+		h.Publish(channel, donation(i+1))
+		<-fast
+	}
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("slow subscriber got %d events before the cutoff, want %d", received, subscriberBuffer)
+	}
+
+	h.Publish(channel, donation(100))
+	select {
+	case e, ok := <-fast:
+		if !ok || e.ID != 100 {
+			t.Errorf("fast subscriber got %+v, %v", e, ok)
+		}
+	case <-time.After(time.Second):
+		t.Error("fast subscriber was cut off too")
+	}
+}
+
+func TestPublishDoesNotWaitForLoad(t *testing.T) {
+	release := make(chan struct{})
+	h := NewHub(10, func(ctx context.Context, channel string, n int) ([]Event, error) {
+		<-release
+		return []Event{donation(1), donation(2)}, nil
+	})
+
+	type result struct {
+		replay []Event
+		err    error
+	}
+	subscribed := make(chan result, 1)
+	go func() {
+		replay, _, cancel, err := h.Subscribe(context.Background(), channel, 0)
+		if err == nil {
+			t.Cleanup(cancel)
+		}
+		subscribed <- result{replay, err}
+	}()
+	// wait for the subscriber to create the feed
+	for {
+		h.mu.Lock()
+		_, ok := h.channels[channel]
+		h.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	published := make(chan struct{})
+	go func() {
+		h.Publish(channel, donation(2))
+		h.Publish(channel, donation(3))
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Publish waited for the load")
+	}
+	close(release)
+
+	r := <-subscribed
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if got := ids(r.replay); !slices.Equal(got, []int64{1, 2, 3}) {
+		t.Errorf("replay %v, want the loaded donations and the new one once", got)
+	}
+}
+
+func TestFeedDroppedWithoutSubscribers(t *testing.T) {
+	h := NewHub(10, staticLoad(1))
+	_, _, first := subscribe(t, h, 0)
+	_, _, second := subscribe(t, h, 0)
+	first()
+	first()
+	if len(h.channels) != 1 {
+		t.Fatal("feed dropped while it has a subscriber")
+	}
+	second()
+	if len(h.channels) != 0 {
+		t.Errorf("%d feeds kept without subscribers", len(h.channels))
+	}
+	// no feed, nothing kept
+	h.Publish(channel, donation(2))
+	if len(h.channels) != 0 {
+		t.Error("Publish created a feed")
+	}
+}
+
+func TestLoadErrorIsRetried(t *testing.T) {
+	var calls atomic.Int64
+	h := NewHub(10, func(ctx context.Context, channel string, n int) ([]Event, error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("database is locked")
+		}
+		return []Event{donation(1)}, nil
+	})
+	if _, _, _, err := h.Subscribe(context.Background(), channel, 0); err == nil {
+		t.Fatal("no error from a failed load")
+	}
+	if len(h.channels) != 0 {
+		t.Error("failed feed was kept")
+	}
+	replay, _, _ := subscribe(t, h, 0)
+	if got := ids(replay); !slices.Equal(got, []int64{1}) {
+		t.Errorf("replay %v after the retry", got)
+	}
+}
@@ -0,0 +1,273 @@
+package health
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// rateWindow is the period config.HealthConfig.MaxPerHour counts alerts in.
+const rateWindow = time.Hour
+
+// Keys of the conditions Check watches, silent channels add the channel.
+const (
+	keyTwitch = "twitch"
+	keyDisk   = "disk"
+	keySilent = "silent:"
+)
+
+// Monitor watches the health of the pipeline and posts alerts about it to an
+// ops channel. Conditions checked by Check are posted once when they start
+// and once when they clear, events reported by Alert are posted as they
+// come. The same alert is not repeated within the cooldown and no more than
+// MaxPerHour alerts are posted. Alerts held back are counted in the next one,
+// conditions held back are retried by the next check.
+type Monitor struct {
+	cfg    config.HealthConfig
+	out    io.Writer
+	logger *slog.Logger
+	now    func() time.Time
+	// freeSpace returns the bytes available at a path.
+	freeSpace func(path string) (uint64, error)
+
+	mu sync.Mutex
+	// lastTwitch is the last time anything arrived from Twitch, or the
+	// connection changed.
+	lastTwitch time.Time
+	twitchErr  error
+	channels   map[string]*channelActivity
+	// active are the conditions posted and not cleared yet.
+	active map[string]bool
+	// lastSent and repeats dedupe alerts by key.
+	lastSent map[string]time.Time
+	repeats  map[string]int
+	// sent are the times of the alerts posted within rateWindow, dropped
+	// counts the alerts held back by the rate limit.
+	sent    []time.Time
+	dropped int
+}
+
+type channelActivity struct {
+	last time.Time
+	// longestQuiet is the longest time between two messages seen so far.
+	longestQuiet time.Duration
+}
+
+// NewMonitor returns a monitor writing its alerts to out, e.g. the ops
+// channel of the Discord backend.
+func NewMonitor(cfg config.HealthConfig, out io.Writer, logger *slog.Logger) *Monitor {
+	m := &Monitor{
+		cfg:       cfg,
+		out:       out,
+		logger:    logger,
+		now:       time.Now,
+		freeSpace: freeSpace,
+		channels:  make(map[string]*channelActivity),
+		active:    make(map[string]bool),
+		lastSent:  make(map[string]time.Time),
+		repeats:   make(map[string]int),
+	}
+	m.lastTwitch = m.now()
+	return m
+}
+
+// TwitchConnected records that the Twitch connection is up.
+func (m *Monitor) TwitchConnected() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastTwitch = m.now()
+	m.twitchErr = nil
+}
+
+// TwitchDisconnected records that the Twitch connection dropped or a
+// reconnect failed with err. The downtime counts from the first of them.
+func (m *Monitor) TwitchDisconnected(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.twitchErr == nil {
+		m.lastTwitch = m.now()
+	}
+	m.twitchErr = err
+	if err == nil {
+		m.twitchErr = errors.New("disconnected")
+	}
+}
+
+// TwitchActivity records a message from Twitch, Twitch pings every few
+// minutes so a connection without any is dead.
+func (m *Monitor) TwitchActivity() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.twitchErr == nil {
+		m.lastTwitch = m.now()
+	}
+}
+
+// ChannelActivity records a chat message in channel.
+func (m *Monitor) ChannelActivity(channel string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	a, ok := m.channels[channel]
+	if !ok {
+		m.channels[channel] = &channelActivity{last: now}
+		return
+	}
+	a.longestQuiet = max(a.longestQuiet, now.Sub(a.last))
+	a.last = now
+}
+
+// Alert posts text unless an alert with the same key was posted within the
+// cooldown or the rate limit is reached.
+func (m *Monitor) Alert(key, text string) {
+	m.mu.Lock()
+	now := m.now()
+	if last, ok := m.lastSent[key]; ok && now.Sub(last) < time.Duration(m.cfg.Cooldown) {
+		m.repeats[key]++
+		m.mu.Unlock()
+		return
+	}
+	if repeats := m.repeats[key]; repeats > 0 {
+		text += fmt.Sprintf(" (%d more since the last alert)", repeats)
+	}
+	message, ok := m.take(now, text)
+	if ok {
+		m.lastSent[key] = now
+		m.repeats[key] = 0
+	} else {
+		m.dropped++
+	}
+	m.mu.Unlock()
+	m.post(message, ok)
+}
+
+// take reserves a place for an alert within the rate limit and returns the
+// message to post, false when the limit is reached. m.mu must be held.
+func (m *Monitor) take(now time.Time, text string) (string, bool) {
+	i := 0
+	for i < len(m.sent) && now.Sub(m.sent[i]) >= rateWindow {
+		i++
+	}
+	m.sent = m.sent[i:]
+	if len(m.sent) >= m.cfg.MaxPerHour {
+		return text, false
+	}
+	m.sent = append(m.sent, now)
+	if m.dropped > 0 {
+		text += fmt.Sprintf("\n%d alerts were held back by the rate limit.", m.dropped)
+		m.dropped = 0
+	}
+	return text, true
+}
+
+func (m *Monitor) post(message string, ok bool) {
+	if !ok {
+		m.logger.Warn("health alert held back by the rate limit", "alert", message)
+		return
+	}
+	m.logger.Warn("health alert", "alert", message)
+	if _, err := fmt.Fprintln(m.out, message); err != nil {
+		m.logger.Error("failed to post health alert", "error", err)
+	}
+}
+
+// Run checks the health every interval until ctx is done, channels returns
+// the tracked channels.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration, channels func() []string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(channels())
+		}
+	}
+}
+
+// Check posts the conditions that started or cleared since the last check.
+func (m *Monitor) Check(channels []string) {
+	type condition struct {
+		key string
+		bad bool
+		// text is posted when the condition starts, resolved when it clears.
+		text, resolved string
+	}
+	var conditions []condition
+
+	m.mu.Lock()
+	now := m.now()
+	if m.cfg.TwitchDown > 0 {
+		down := now.Sub(m.lastTwitch)
+		text := fmt.Sprintf("No messages from Twitch for %s.", down.Round(time.Minute))
+		if m.twitchErr != nil {
+			text = fmt.Sprintf("Twitch has been disconnected for %s: %v", down.Round(time.Minute), m.twitchErr)
+		}
+		conditions = append(conditions, condition{keyTwitch, down > time.Duration(m.cfg.TwitchDown), text, "Twitch messages arrive again."})
+	}
+	if m.cfg.Silence > 0 {
+		tracked := make(map[string]bool, len(channels))
+		for _, channel := range channels {
+			tracked[channel] = true
+			a, ok := m.channels[channel]
+			if !ok {
+				// quiet since it started being tracked
+				a = &channelActivity{last: now}
+				m.channels[channel] = a
+			}
+			quiet := now.Sub(a.last)
+			limit := max(time.Duration(m.cfg.Silence), 2*a.longestQuiet)
+			conditions = append(conditions, condition{keySilent + channel, quiet > limit,
+				fmt.Sprintf("No chat messages in %s for %s, usually at most %s.", channel, quiet.Round(time.Minute), a.longestQuiet.Round(time.Minute)),
+				fmt.Sprintf("Chat messages arrive in %s again.", channel)})
+		}
+		for channel := range m.channels {
+			if !tracked[channel] {
+				delete(m.channels, channel)
+				delete(m.active, keySilent+channel)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if m.cfg.MinFreeDiskMB > 0 {
+		free, err := m.freeSpace(m.cfg.DiskPath)
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+		case err != nil:
+			m.logger.Error("failed to check free disk space", "path", m.cfg.DiskPath, "error", err)
+		default:
+			freeMB := free >> 20
+			conditions = append(conditions, condition{keyDisk, freeMB < uint64(m.cfg.MinFreeDiskMB),
+				fmt.Sprintf("Only %d MB free on the disk of %s, below %d MB.", freeMB, m.cfg.DiskPath, m.cfg.MinFreeDiskMB),
+				fmt.Sprintf("%d MB free on the disk of %s again.", freeMB, m.cfg.DiskPath)})
+		}
+	}
+
+	for _, c := range conditions {
+		m.mu.Lock()
+		var message string
+		var ok bool
+		switch active := m.active[c.key]; {
+		case c.bad && !active:
+			message, ok = m.take(now, c.text)
+		case !c.bad && active:
+			message, ok = m.take(now, c.resolved)
+		default:
+			m.mu.Unlock()
+			continue
+		}
+		if ok {
+			// a condition held back stays unposted until a check gets through
+			m.active[c.key] = c.bad
+		}
+		m.mu.Unlock()
+		m.post(message, ok)
+	}
+}
@@ -0,0 +1,187 @@
+package health
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// posts collects every alert written, one per write.
+type posts []string
+
+func (p *posts) Write(b []byte) (int, error) {
+	*p = append(*p, strings.TrimSuffix(string(b), "\n"))
+	return len(b), nil
+}
+
+// clock is a settable time for Monitor.now.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestMonitor returns a monitor with every check off unless cfg turns it
+// on, its clock and the alerts it posts.
+func newTestMonitor(cfg config.HealthConfig) (*Monitor, *clock, *posts) {
+	if cfg.MaxPerHour == 0 {
+		cfg.MaxPerHour = 100
+	}
+	out := &posts{}
+	c := &clock{t: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	m := NewMonitor(cfg, out, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.now = c.now
+	m.lastTwitch = c.now()
+	m.freeSpace = func(string) (uint64, error) { return 0, errors.ErrUnsupported }
+	return m, c, out
+}
+
+func (p *posts) take(t *testing.T, want ...string) {
+	t.Helper()
+	if len(*p) != len(want) {
+		t.Fatalf("posted %q, want %d alerts", *p, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains((*p)[i], w) {
+			t.Errorf("alert %q does not contain %q", (*p)[i], w)
+		}
+	}
+	*p = nil
+}
+
+func TestAlertCooldown(t *testing.T) {
+	m, clock, out := newTestMonitor(config.HealthConfig{Cooldown: config.Duration(30 * time.Minute)})
+
+	m.Alert("logfile", "cannot write")
+	out.take(t, "cannot write")
+
+	clock.advance(10 * time.Minute)
+	m.Alert("logfile", "cannot write")
+	m.Alert("other", "something else")
+	clock.advance(10 * time.Minute)
+	m.Alert("logfile", "cannot write")
+	out.take(t, "something else")
+
+	clock.advance(11 * time.Minute)
+	m.Alert("logfile", "cannot write")
+	out.take(t, "cannot write (2 more since the last alert)")
+
+	clock.advance(31 * time.Minute)
+	m.Alert("logfile", "cannot write")
+	if len(*out) != 1 || (*out)[0] != "cannot write" {
+		t.Errorf("posted %q, want the repeats counted only once", *out)
+	}
+}
+
+func TestAlertRateLimit(t *testing.T) {
+	m, clock, out := newTestMonitor(config.HealthConfig{MaxPerHour: 2})
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Alert(key, "alert "+key)
+		clock.advance(time.Minute)
+	}
+	out.take(t, "alert a", "alert b")
+
+	clock.advance(time.Hour)
+	m.Alert("e", "alert e")
+	out.take(t, "alert e\n2 alerts were held back by the rate limit.")
+
+	m.Alert("f", "alert f")
+	if len(*out) != 1 || (*out)[0] != "alert f" {
+		t.Errorf("posted %q, want the held back count only once", *out)
+	}
+}
+
+func TestCheckPostsConditionOnStartAndClear(t *testing.T) {
+	m, clock, out := newTestMonitor(config.HealthConfig{DiskPath: "/data", MinFreeDiskMB: 500})
+	free := uint64(100 << 20)
+	m.freeSpace = func(path string) (uint64, error) {
+		if path != "/data" {
+			t.Errorf("free space checked for %s", path)
+		}
+		return free, nil
+	}
+
+	m.Check(nil)
+	out.take(t, "Only 100 MB free on the disk of /data, below 500 MB.")
+	clock.advance(time.Minute)
+	m.Check(nil)
+	out.take(t)
+
+	free = 1000 << 20
+	m.Check(nil)
+	out.take(t, "1000 MB free on the disk of /data again.")
+	m.Check(nil)
+	out.take(t)
+}
+
+func TestCheckRetriesConditionHeldBack(t *testing.T) {
+	m, clock, out := newTestMonitor(config.HealthConfig{MaxPerHour: 1, DiskPath: ".", MinFreeDiskMB: 500})
+	m.freeSpace = func(string) (uint64, error) { return 0, nil }
+
+	m.Alert("logfile", "cannot write")
+	m.Check(nil)
+	out.take(t, "cannot write")
+
+	clock.advance(time.Hour)
+	m.Check(nil)
+	out.take(t, "Only 0 MB free")
+}
+
+func TestCheckTwitchDown(t *testing.T) {
+	m, clock, out := newTestMonitor(config.HealthConfig{TwitchDown: config.Duration(10 * time.Minute)})
+
+	m.TwitchConnected()
+	m.TwitchDisconnected(errors.New("connection reset"))
+	// failed reconnects don't restart the downtime
+	for range 6 {
+		clock.advance(2 * time.Minute)
+		m.TwitchDisconnected(errors.New("connection refused"))
+		m.Check(nil)
+	}
+	out.take(t, "Twitch has been disconnected for 12m0s: connection refused")
+
+	m.TwitchConnected()
+	m.Check(nil)
+	out.take(t, "Twitch messages arrive again.")
+
+	// connected but nothing arrives
+	clock.advance(11 * time.Minute)
+	m.Check(nil)
+	out.take(t, "No messages from Twitch for 11m0s.")
+	m.TwitchActivity()
+	m.Check(nil)
+	out.take(t, "Twitch messages arrive again.")
+}
+
+func TestCheckSilenceLearnsQuietTime(t *testing.T) {
+	m, clock, out := newTestMonitor(config.HealthConfig{Silence: config.Duration(time.Hour)})
+	channels := []string{"#tartancz"}
+
+	m.ChannelActivity("#tartancz")
+	clock.advance(3 * time.Hour)
+	m.ChannelActivity("#tartancz")
+
+	// quiet for longer than silence but not twice the longest quiet time
+	clock.advance(5 * time.Hour)
+	m.Check(channels)
+	out.take(t)
+
+	clock.advance(2 * time.Hour)
+	m.Check(channels)
+	out.take(t, "No chat messages in #tartancz for 7h0m0s, usually at most 3h0m0s.")
+
+	m.ChannelActivity("#tartancz")
+	m.Check(channels)
+	out.take(t, "Chat messages arrive in #tartancz again.")
+
+	// channels no longer tracked are forgotten
+	m.Check(nil)
+	if _, ok := m.channels["#tartancz"]; ok {
+		t.Error("untracked channel is still watched")
+	}
+}
@@ -0,0 +1,286 @@
+package importer
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"TwitchDonoCalculator/internal/validator"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SourcePrefix marks imported donations, the profile name is appended, e.g.
+// "import:streamlabs". Live captured donations have the source "live".
+const SourcePrefix = "import:"
+
+type Options struct {
+	// Channel is used for rows without a channel column.
+	Channel string
+	// Currency is the currency donations are stored in. Rows in another
+	// currency need a rate in Rates.
+	Currency string
+	// Rates converts one unit of a currency into Currency.
+	Rates map[string]float64
+	// Location is used for timestamps without a timezone.
+	Location *time.Location
+}
+
+// Record is one donation read from a CSV file, with its amount already
+// converted to Options.Currency.
+type Record struct {
+	Line      int
+	Channel   string
+	Donor     string
+	Amount    int64
+	Text      string
+	Timestamp time.Time
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	time.DateOnly,
+}
+
+// Read parses every row of r with profile. Problems are collected into the
+// returned validator under "line N" keys instead of stopping at the first.
+func Read(r io.Reader, profile Profile, opts Options) ([]Record, *validator.Validator) {
+	v := &validator.Validator{}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		v.AddNonFieldError(fmt.Sprintf("failed to read header: %v", err))
+		return nil, v
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns := profile.resolve(header)
+	for _, field := range []string{FieldTimestamp, FieldDonor, FieldAmount} {
+		if _, ok := columns[field]; !ok {
+			v.AddFieldError(field, fmt.Sprintf("no column found for profile %s, tried %s", profile.Name, strings.Join(profile.Columns[field], ", ")))
+		}
+	}
+	if _, ok := columns[FieldChannel]; !ok && opts.Channel == "" {
+		v.AddFieldError(FieldChannel, "file has no channel column, set a default channel")
+	}
+	if !v.Valid() {
+		return nil, v
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			v.AddFieldError(fmt.Sprintf("line %d", line), err.Error())
+			continue
+		}
+		record, err := parseRow(row, columns, opts)
+		if err != nil {
+			v.AddFieldError(fmt.Sprintf("line %d", line), err.Error())
+			continue
+		}
+		record.Line = line
+		records = append(records, record)
+	}
+	return records, v
+}
+
+func parseRow(row []string, columns map[string]int, opts Options) (Record, error) {
+	get := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	record := Record{
+		Channel: opts.Channel,
+		Donor:   get(FieldDonor),
+		Text:    get(FieldMessage),
+	}
+	if channel := get(FieldChannel); channel != "" {
+		record.Channel = "#" + strings.TrimPrefix(strings.ToLower(channel), "#")
+	}
+	if record.Donor == "" {
+		return Record{}, errors.New("donor is empty")
+	}
+
+	timestamp, err := parseTime(get(FieldTimestamp), opts.Location)
+	if err != nil {
+		return Record{}, err
+	}
+	record.Timestamp = timestamp
+
+	amount, err := parseAmount(get(FieldAmount))
+	if err != nil {
+		return Record{}, err
+	}
+	currency := strings.ToUpper(get(FieldCurrency))
+	if currency != "" && currency != opts.Currency {
+		rate, ok := opts.Rates[currency]
+		if !ok {
+			return Record{}, fmt.Errorf("no rate for currency %s", currency)
+		}
+		amount *= rate
+	}
+	record.Amount = int64(math.Round(amount))
+	if record.Amount <= 0 {
+		return Record{}, fmt.Errorf("amount %q is not positive", get(FieldAmount))
+	}
+	return record, nil
+}
+
+func parseTime(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("timestamp is empty")
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			// donations are stored with second precision
+			return t.UTC().Truncate(time.Second), nil
+		}
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown timestamp format %q", value)
+}
+
+// parseAmount accepts "1234.5", "1,234.50", "1.234,50", "1234,5", a sign and
+// a leading or trailing currency symbol. The last "." or "," is the decimal
+// separator when 1 or 2 digits follow it, other separators and spaces group
+// thousands. Anything else, e.g. "1.2345" or "1,23.4", is rejected as
+// ambiguous.
+func parseAmount(value string) (float64, error) {
+	invalid := fmt.Errorf("invalid amount %q", value)
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' }
+	isSeparator := func(r rune) bool { return r == '.' || r == ',' }
+	cleaned := strings.TrimFunc(value, func(r rune) bool {
+		return !isDigit(r) && !isSeparator(r) && r != '-'
+	})
+	negative := strings.HasPrefix(cleaned, "-")
+	// the sign may come before the currency symbol, "-$5"
+	cleaned = strings.TrimLeftFunc(cleaned, func(r rune) bool {
+		return !isDigit(r) && !isSeparator(r)
+	})
+	cleaned = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, cleaned)
+	if cleaned == "" || strings.IndexFunc(cleaned, func(r rune) bool {
+		return !isDigit(r) && !isSeparator(r)
+	}) >= 0 {
+		return 0, invalid
+	}
+
+	integer, fraction := cleaned, ""
+	if i := strings.LastIndexAny(cleaned, ".,"); i >= 0 && len(cleaned)-i-1 <= 2 {
+		integer, fraction = cleaned[:i], cleaned[i+1:]
+		if fraction == "" || strings.ContainsRune(integer, rune(cleaned[i])) {
+			return 0, invalid
+		}
+	}
+	if strings.Contains(integer, ".") && strings.Contains(integer, ",") {
+		return 0, invalid
+	}
+	groups := strings.FieldsFunc(integer, isSeparator)
+	if len(groups) == 0 || strings.Count(integer, ".")+strings.Count(integer, ",") != len(groups)-1 {
+		return 0, invalid
+	}
+	for i, group := range groups[1:] {
+		if len(group) != 3 || i == 0 && len(groups[0]) > 3 {
+			return 0, invalid
+		}
+	}
+
+	cleaned = strings.Join(groups, "")
+	if fraction != "" {
+		cleaned += "." + fraction
+	}
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, invalid
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// Result counts what Import did, or would do in a dry run.
+type Result struct {
+	Imported   int
+	Duplicates int
+}
+
+// Import stores records in a single transaction, skipping the ones that match
+// an existing donation (same channel, donor, amount and second), including
+// ones inserted earlier in the same import. A dry run rolls back at the end.
+// The donations get the source SourcePrefix+profile and the profile as user,
+// where live ones have the bot that announced them.
+func Import(ctx context.Context, database *sql.DB, records []Record, profile string, dryRun bool) (Result, error) {
+	var result Result
+	errDryRun := errors.New("dry run")
+
+	err := db.RunInTx(ctx, database, func(q *db.Queries) error {
+		for _, r := range records {
+			count, err := q.CountDuplicateDonations(ctx, db.CountDuplicateDonationsParams{
+				Channel:   r.Channel,
+				SendFrom:  r.Donor,
+				Amount:    r.Amount,
+				DonatedAt: r.Timestamp,
+			})
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				result.Duplicates++
+				continue
+			}
+
+			err = q.ImportDonation(ctx, db.ImportDonationParams{
+				User:      profile,
+				Channel:   r.Channel,
+				SendFrom:  r.Donor,
+				Amount:    r.Amount,
+				Text:      r.Text,
+				Timestamp: r.Timestamp,
+				Source:    SourcePrefix + profile,
+			})
+			if err != nil {
+				return fmt.Errorf("line %d: %w", r.Line, err)
+			}
+			result.Imported++
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return result, err
+}
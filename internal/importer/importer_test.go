@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		{"1234", 1234, true},
+		{"1234.5", 1234.5, true},
+		{"1234,5", 1234.5, true},
+		{"1,234.50", 1234.5, true},
+		{"1.234,50", 1234.5, true},
+		{"1,234", 1234, true},
+		{"1.234", 1234, true},
+		{"1.234.567", 1234567, true},
+		{"1 234,50 Kč", 1234.5, true},
+		{"$12.99", 12.99, true},
+		{"12,99 €", 12.99, true},
+		{"-5.00", -5, true},
+		{"-$5", -5, true},
+		{"$-5", -5, true},
+		{"", 0, false},
+		{"abc", 0, false},
+		{"1.2345", 0, false},
+		{"1,23.4", 0, false},
+		{"1.234.5", 0, false},
+		{"1.234,567", 0, false},
+		{"12345,678", 0, false},
+		{"1,2,3", 0, false},
+		{"1.", 0, false},
+		{".5", 0, false},
+		{"5-", 0, false},
+		{"1-2", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v; want %v, ok %v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReadReportsInvalidAmounts(t *testing.T) {
+	profile, err := GetProfile("generic", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv := "date,donor,amount\n" +
+		"2025-06-12,alice,\"1.234,50\"\n" +
+		"2025-06-12,bob,-5.00\n" +
+		"2025-06-12,carol,1.2345\n"
+	records, v := Read(strings.NewReader(csv), profile, Options{Channel: "#tartancz", Currency: "CZK", Location: time.UTC})
+	if len(records) != 1 || records[0].Amount != 1235 {
+		t.Errorf("records = %+v, want one of 1235", records)
+	}
+	for _, key := range []string{"line 3", "line 4"} {
+		if _, ok := v.FieldErrors[key]; !ok {
+			t.Errorf("no error for %s, got %v", key, v.FieldErrors)
+		}
+	}
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	database, err := db.OpenDB(config.DBConfig{DSN: filepath.Join(t.TempDir(), "db.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	db.RunMigrations(database)
+	return database
+}
+
+func TestImport(t *testing.T) {
+	database := openTestDB(t)
+	ctx := context.Background()
+	at := time.Date(2024, 5, 1, 18, 30, 0, 0, time.UTC)
+	err := db.New(database).ImportDonation(ctx, db.ImportDonationParams{
+		User: "donobot", Channel: "#tartancz", SendFrom: "alice", Amount: 100, Text: "live", Timestamp: at, Source: "live",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []Record{
+		// already captured live
+		{Line: 2, Channel: "#tartancz", Donor: "alice", Amount: 100, Timestamp: at},
+		{Line: 3, Channel: "#tartancz", Donor: "bob", Amount: 50, Timestamp: at},
+		// the same donation twice in the file
+		{Line: 4, Channel: "#tartancz", Donor: "bob", Amount: 50, Timestamp: at},
+		{Line: 5, Channel: "#tartancz", Donor: "bob", Amount: 50, Timestamp: at.Add(time.Second)},
+	}
+	count := func() int {
+		var n int
+		if err := database.QueryRow("SELECT COUNT(*) FROM donation").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	result, err := Import(ctx, database, records, "streamlabs", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Result{Imported: 2, Duplicates: 2}); result != want {
+		t.Errorf("dry run result %+v, want %+v", result, want)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("%d donations after a dry run, want 1", n)
+	}
+
+	result, err = Import(ctx, database, records, "streamlabs", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Result{Imported: 2, Duplicates: 2}); result != want {
+		t.Errorf("result %+v, want %+v", result, want)
+	}
+	rows, err := database.Query("SELECT user, source FROM donation WHERE send_from = 'bob'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var imported int
+	for rows.Next() {
+		var user, source string
+		if err := rows.Scan(&user, &source); err != nil {
+			t.Fatal(err)
+		}
+		if user != "streamlabs" || source != "import:streamlabs" {
+			t.Errorf("imported donation has user %q and source %q", user, source)
+		}
+		imported++
+	}
+	if imported != 2 {
+		t.Errorf("%d donations of bob, want 2", imported)
+	}
+
+	result, err = Import(ctx, database, records, "streamlabs", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Result{Duplicates: 4}); result != want {
+		t.Errorf("second import result %+v, want %+v", result, want)
+	}
+	if n := count(); n != 3 {
+		t.Errorf("%d donations after importing twice, want 3", n)
+	}
+}
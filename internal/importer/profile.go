@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fields a column can be mapped to. Timestamp, donor and amount are required,
+// channel is required unless a default channel is given.
+const (
+	FieldTimestamp = "timestamp"
+	FieldDonor     = "donor"
+	FieldAmount    = "amount"
+	FieldCurrency  = "currency"
+	FieldMessage   = "message"
+	FieldChannel   = "channel"
+)
+
+var fields = []string{FieldTimestamp, FieldDonor, FieldAmount, FieldCurrency, FieldMessage, FieldChannel}
+
+// Profile maps the columns of one kind of CSV export to donation fields. Each
+// field lists the header names it is found under, matched case-insensitively.
+type Profile struct {
+	Name    string
+	Columns map[string][]string
+}
+
+var profiles = map[string]Profile{
+	"streamelements": {
+		Name: "streamelements",
+		Columns: map[string][]string{
+			FieldTimestamp: {"createdAt", "date", "Date"},
+			FieldDonor:     {"donation.user.username", "username", "name"},
+			FieldAmount:    {"donation.amount", "amount"},
+			FieldCurrency:  {"donation.currency", "currency"},
+			FieldMessage:   {"donation.message", "message"},
+		},
+	},
+	"streamlabs": {
+		Name: "streamlabs",
+		Columns: map[string][]string{
+			FieldTimestamp: {"Date", "created_at", "Donation Date"},
+			FieldDonor:     {"Donor", "Name", "donator"},
+			FieldAmount:    {"Amount", "Donation Amount"},
+			FieldCurrency:  {"Currency"},
+			FieldMessage:   {"Message", "Comment"},
+		},
+	},
+	"generic": {
+		Name: "generic",
+		Columns: map[string][]string{
+			FieldTimestamp: {"timestamp", "date", "time"},
+			FieldDonor:     {"donor", "send_from", "username", "name"},
+			FieldAmount:    {"amount"},
+			FieldCurrency:  {"currency"},
+			FieldMessage:   {"message", "text"},
+			FieldChannel:   {"channel"},
+		},
+	},
+}
+
+// ProfileNames returns the known profile names, sorted.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// GetProfile returns the profile called name with mapping applied on top.
+// mapping is a comma separated list of field=column pairs, e.g.
+// "amount=Total,donor=From", and replaces the header names of those fields.
+func GetProfile(name, mapping string) (Profile, error) {
+	base, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q, use %s", name, strings.Join(ProfileNames(), ", "))
+	}
+	p := Profile{Name: base.Name, Columns: make(map[string][]string, len(base.Columns))}
+	for field, columns := range base.Columns {
+		p.Columns[field] = columns
+	}
+	if mapping == "" {
+		return p, nil
+	}
+	for _, pair := range strings.Split(mapping, ",") {
+		field, column, found := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !found || column == "" {
+			return Profile{}, fmt.Errorf("invalid mapping %q, use field=column", pair)
+		}
+		if !slices.Contains(fields, field) {
+			return Profile{}, fmt.Errorf("unknown field %q in mapping, use %s", field, strings.Join(fields, ", "))
+		}
+		p.Columns[field] = []string{column}
+	}
+	return p, nil
+}
+
+// resolve finds the index of every mapped field in header. Fields without a
+// matching column are left out.
+func (p Profile) resolve(header []string) map[string]int {
+	indexes := make(map[string]int)
+	for field, names := range p.Columns {
+		for _, name := range names {
+			i := slices.IndexFunc(header, func(h string) bool {
+				return strings.EqualFold(strings.TrimSpace(h), name)
+			})
+			if i >= 0 {
+				indexes[field] = i
+				break
+			}
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,29 @@
+package session
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeSource is a LiveStatusSource whose status is set by hand, for local
+// runs and tests without access to the Twitch API.
+type FakeSource struct {
+	mu   sync.Mutex
+	live map[string]bool
+}
+
+func NewFakeSource() *FakeSource {
+	return &FakeSource{live: make(map[string]bool)}
+}
+
+func (f *FakeSource) SetLive(channel string, live bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.live[channel] = live
+}
+
+func (f *FakeSource) IsLive(_ context.Context, channel string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.live[channel], nil
+}
@@ -0,0 +1,69 @@
+package session
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HelixSource is a LiveStatusSource asking the Twitch Helix API whether a
+// channel is broadcasting.
+type HelixSource struct {
+	url      string
+	clientID string
+	token    string
+	client   *http.Client
+}
+
+func NewHelixSource(cfg config.SessionConfig, client *http.Client) *HelixSource {
+	return &HelixSource{
+		url:      strings.TrimSuffix(cfg.HelixURL, "/"),
+		clientID: cfg.ClientID,
+		token:    cfg.Token,
+		client:   client,
+	}
+}
+
+// IsLive reports whether channel, e.g. "#tartancz", has a live stream.
+func (h *HelixSource) IsLive(ctx context.Context, channel string) (bool, error) {
+	query := url.Values{
+		"user_login": {strings.TrimPrefix(channel, "#")},
+		"type":       {"live"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url+"/streams?"+query.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Client-Id", h.clientID)
+	req.Header.Set("Authorization", "Bearer "+h.token)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("Twitch API returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var streams struct {
+		Data []struct {
+			Type string `json:"type"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&streams); err != nil {
+		return false, fmt.Errorf("invalid Twitch API response: %w", err)
+	}
+	for _, s := range streams.Data {
+		if s.Type == "live" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,201 @@
+package session
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Values of stream_session.source.
+const (
+	// SourceActivity sessions are inferred from gaps in chat activity.
+	SourceActivity = "activity"
+	// SourceLive sessions follow a LiveStatusSource.
+	SourceLive = "live"
+)
+
+// touchInterval throttles how often last_activity_at is written, chat can
+// produce many messages per second.
+const touchInterval = time.Minute
+
+// LiveStatusSource tells whether a channel is broadcasting right now.
+type LiveStatusSource interface {
+	IsLive(ctx context.Context, channel string) (bool, error)
+}
+
+// Tracker keeps one open stream session per channel. Without a source a
+// session starts with the first chat activity and ends once the channel is
+// quiet for longer than gap. With a source, sessions start and end when Poll
+// sees the live status change and chat activity only extends them.
+type Tracker struct {
+	q      *db.Queries
+	gap    time.Duration
+	source LiveStatusSource
+	now    func() time.Time
+
+	mu   sync.Mutex
+	open map[string]*openSession
+}
+
+type openSession struct {
+	id           int64
+	lastActivity time.Time
+	// lastWritten is the last_activity_at stored in the database.
+	lastWritten time.Time
+}
+
+// NewTracker returns a tracker storing sessions through q. source may be nil
+// to infer sessions from chat activity only.
+func NewTracker(q *db.Queries, gap time.Duration, source LiveStatusSource) *Tracker {
+	return &Tracker{
+		q:      q,
+		gap:    gap,
+		source: source,
+		now:    time.Now,
+		open:   make(map[string]*openSession),
+	}
+}
+
+// Touch records chat activity in channel and returns the id of the session
+// it belongs to, or 0 when the channel is not live according to the source.
+func (t *Tracker) Touch(ctx context.Context, channel string) (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().UTC()
+	s, err := t.load(ctx, channel)
+	if err != nil {
+		return 0, err
+	}
+
+	if t.source == nil && s != nil && now.Sub(s.lastActivity) > t.gap {
+		if err := t.end(ctx, channel, s, s.lastActivity); err != nil {
+			return 0, err
+		}
+		s = nil
+	}
+	if s == nil {
+		if t.source != nil {
+			return 0, nil
+		}
+		return t.start(ctx, channel, SourceActivity, now)
+	}
+
+	s.lastActivity = now
+	if now.Sub(s.lastWritten) >= touchInterval {
+		err := t.q.TouchStreamSession(ctx, db.TouchStreamSessionParams{
+			LastActivityAt: now,
+			ID:             s.id,
+		})
+		if err != nil {
+			return 0, err
+		}
+		s.lastWritten = now
+	}
+	return s.id, nil
+}
+
+// Poll asks the source whether channel is live and starts or ends its
+// session accordingly. Without a source it ends a session that has been
+// idle for longer than gap.
+func (t *Tracker) Poll(ctx context.Context, channel string) error {
+	live := false
+	if t.source != nil {
+		var err error
+		live, err = t.source.IsLive(ctx, channel)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().UTC()
+	s, err := t.load(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case t.source == nil:
+		if s != nil && now.Sub(s.lastActivity) > t.gap {
+			return t.end(ctx, channel, s, s.lastActivity)
+		}
+	case live && s == nil:
+		_, err := t.start(ctx, channel, SourceLive, now)
+		return err
+	case !live && s != nil:
+		return t.end(ctx, channel, s, now)
+	}
+	return nil
+}
+
+// Run polls every channel returned by channels each interval until ctx is
+// done. Errors are logged, a failing channel does not stop the others.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration, channels func() []string, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, channel := range channels() {
+			if err := t.Poll(ctx, channel); err != nil {
+				logger.Error("failed to update stream session", "channel", channel, "error", err)
+			}
+		}
+	}
+}
+
+// load returns the open session of channel, reading it from the database the
+// first time so sessions survive a restart. It returns nil when there is none.
+func (t *Tracker) load(ctx context.Context, channel string) (*openSession, error) {
+	if s, ok := t.open[channel]; ok {
+		return s, nil
+	}
+	row, err := t.q.GetOpenStreamSession(ctx, channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		t.open[channel] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &openSession{id: row.ID, lastActivity: row.LastActivityAt, lastWritten: row.LastActivityAt}
+	t.open[channel] = s
+	return s, nil
+}
+
+func (t *Tracker) start(ctx context.Context, channel, source string, now time.Time) (int64, error) {
+	row, err := t.q.CreateStreamSession(ctx, db.CreateStreamSessionParams{
+		Channel:        channel,
+		Source:         source,
+		StartedAt:      now,
+		LastActivityAt: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	t.open[channel] = &openSession{id: row.ID, lastActivity: now, lastWritten: now}
+	return row.ID, nil
+}
+
+func (t *Tracker) end(ctx context.Context, channel string, s *openSession, endedAt time.Time) error {
+	err := t.q.EndStreamSession(ctx, db.EndStreamSessionParams{
+		LastActivityAt: s.lastActivity,
+		EndedAt:        sql.NullTime{Time: endedAt, Valid: true},
+		ID:             s.id,
+	})
+	if err != nil {
+		return err
+	}
+	t.open[channel] = nil
+	return nil
+}
@@ -0,0 +1,200 @@
+package session
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const channel = "#tartancz"
+
+// newTestTracker returns a tracker on a temporary database with its clock
+// at the returned time.
+func newTestTracker(t *testing.T, source LiveStatusSource) (*Tracker, *time.Time) {
+	t.Helper()
+	cfg := config.Default().DB
+	cfg.DSN = filepath.Join(t.TempDir(), "db.db")
+	database, err := db.OpenDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	db.RunMigrations(database)
+
+	now := time.Date(2026, 6, 12, 18, 0, 0, 0, time.UTC)
+	tracker := NewTracker(db.New(database), 30*time.Minute, source)
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func touch(t *testing.T, tracker *Tracker) int64 {
+	t.Helper()
+	id, err := tracker.Touch(context.Background(), channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func poll(t *testing.T, tracker *Tracker) {
+	t.Helper()
+	if err := tracker.Poll(context.Background(), channel); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getOpenSession returns the open session of channel, if any.
+func getOpenSession(t *testing.T, tracker *Tracker) (db.StreamSession, bool) {
+	t.Helper()
+	s, err := tracker.q.GetOpenStreamSession(context.Background(), channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, true
+}
+
+func TestTrackerGap(t *testing.T) {
+	tracker, now := newTestTracker(t, nil)
+	start := *now
+	first := touch(t, tracker)
+	if first == 0 {
+		t.Fatal("first activity started no session")
+	}
+
+	*now = now.Add(20 * time.Minute)
+	if id := touch(t, tracker); id != first {
+		t.Fatalf("activity within the gap got session %d, want %d", id, first)
+	}
+	lastActivity := *now
+
+	// Poll ends the session once the gap passed without activity
+	*now = now.Add(29 * time.Minute)
+	poll(t, tracker)
+	if _, ok := getOpenSession(t, tracker); !ok {
+		t.Fatal("session ended before the gap passed")
+	}
+	*now = now.Add(2 * time.Minute)
+	poll(t, tracker)
+	if _, ok := getOpenSession(t, tracker); ok {
+		t.Fatal("session still open after the gap")
+	}
+	ended, err := tracker.q.GetLastStreamSession(context.Background(), channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ended.ID != first || ended.Source != SourceActivity || !ended.StartedAt.Equal(start) || !ended.EndedAt.Valid || !ended.EndedAt.Time.Equal(lastActivity) {
+		t.Errorf("ended session %+v, want it from %v to the last activity at %v", ended, start, lastActivity)
+	}
+
+	second := touch(t, tracker)
+	if second == 0 || second == first {
+		t.Fatalf("activity after the gap got session %d, want a new one", second)
+	}
+
+	// Touch also ends a session that was not polled in time
+	*now = now.Add(time.Hour)
+	if third := touch(t, tracker); third == second || third == 0 {
+		t.Fatalf("activity an hour later got session %d, want a new one", third)
+	}
+}
+
+func TestTrackerSurvivesRestart(t *testing.T) {
+	tracker, now := newTestTracker(t, nil)
+	id := touch(t, tracker)
+
+	restarted := NewTracker(tracker.q, tracker.gap, nil)
+	restarted.now = tracker.now
+	*now = now.Add(10 * time.Minute)
+	if got := touch(t, restarted); got != id {
+		t.Errorf("after a restart got session %d, want %d", got, id)
+	}
+}
+
+func TestTrackerSource(t *testing.T) {
+	source := NewFakeSource()
+	tracker, now := newTestTracker(t, source)
+
+	if id := touch(t, tracker); id != 0 {
+		t.Fatalf("activity while offline got session %d", id)
+	}
+	poll(t, tracker)
+	if _, ok := getOpenSession(t, tracker); ok {
+		t.Fatal("offline poll started a session")
+	}
+
+	source.SetLive(channel, true)
+	*now = now.Add(time.Minute)
+	poll(t, tracker)
+	s, ok := getOpenSession(t, tracker)
+	if !ok || s.Source != SourceLive || !s.StartedAt.Equal(*now) {
+		t.Fatalf("live poll opened %+v, ok %v", s, ok)
+	}
+
+	// chat gaps don't end a live session
+	*now = now.Add(2 * time.Hour)
+	if id := touch(t, tracker); id != s.ID {
+		t.Fatalf("activity while live got session %d, want %d", id, s.ID)
+	}
+	poll(t, tracker)
+	if _, ok := getOpenSession(t, tracker); !ok {
+		t.Fatal("live session ended by a chat gap")
+	}
+
+	source.SetLive(channel, false)
+	*now = now.Add(time.Minute)
+	poll(t, tracker)
+	if _, ok := getOpenSession(t, tracker); ok {
+		t.Fatal("session still open after going offline")
+	}
+	ended, err := tracker.q.GetLastStreamSession(context.Background(), channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ended.EndedAt.Valid || !ended.EndedAt.Time.Equal(*now) {
+		t.Errorf("ended session %+v, want it ended at %v", ended, *now)
+	}
+}
+
+func TestHelixSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/streams" || r.Header.Get("Client-Id") != "client" || r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, `{"message":"invalid request"}`, http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("user_login") {
+		case "live":
+			w.Write([]byte(`{"data":[{"type":"live","user_login":"live"}],"pagination":{}}`))
+		case "broken":
+			w.Write([]byte(`{"data":`))
+		default:
+			w.Write([]byte(`{"data":[],"pagination":{}}`))
+		}
+	}))
+	defer srv.Close()
+	source := NewHelixSource(config.SessionConfig{HelixURL: srv.URL + "/", ClientID: "client", Token: "token"}, srv.Client())
+	ctx := context.Background()
+
+	if live, err := source.IsLive(ctx, "#live"); err != nil || !live {
+		t.Errorf("#live: %v, %v", live, err)
+	}
+	if live, err := source.IsLive(ctx, "#offline"); err != nil || live {
+		t.Errorf("#offline: %v, %v", live, err)
+	}
+	if _, err := source.IsLive(ctx, "#broken"); err == nil {
+		t.Error("#broken: no error for an invalid response")
+	}
+	source.token = "wrong"
+	if _, err := source.IsLive(ctx, "#live"); err == nil {
+		t.Error("no error for a 401")
+	}
+}
@@ -0,0 +1,45 @@
+package stats
+
+import (
+	"strings"
+)
+
+// sparkRamp goes from the lowest to the highest value, plain ASCII so it
+// renders the same in every Discord client font.
+const sparkRamp = "_.-~=+*#"
+
+// Sparkline renders values as a single line, one character per value.
+func Sparkline(values []int64) string {
+	maxValue := maxOf(values)
+	var b strings.Builder
+	for _, v := range values {
+		if maxValue == 0 || v <= 0 {
+			b.WriteByte(' ')
+			continue
+		}
+		i := int(v * int64(len(sparkRamp)-1) / maxValue)
+		b.WriteByte(sparkRamp[i])
+	}
+	return b.String()
+}
+
+// Bar renders value as a horizontal bar of up to width characters relative
+// to maxValue.
+func Bar(value, maxValue int64, width int) string {
+	if maxValue <= 0 || value <= 0 {
+		return ""
+	}
+	n := int(value * int64(width) / maxValue)
+	if n == 0 {
+		n = 1
+	}
+	return strings.Repeat("#", n)
+}
+
+func maxOf(values []int64) int64 {
+	var m int64
+	for _, v := range values {
+		m = max(m, v)
+	}
+	return m
+}
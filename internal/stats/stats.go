@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type Interval string
+
+const (
+	Hour  Interval = "hour"
+	Day   Interval = "day"
+	Week  Interval = "week"
+	Month Interval = "month"
+)
+
+func ParseInterval(s string) (Interval, error) {
+	switch i := Interval(s); i {
+	case Hour, Day, Week, Month:
+		return i, nil
+	}
+	return "", fmt.Errorf("unknown interval %q, use hour, day, week or month", s)
+}
+
+// Truncate returns the start of the bucket t falls into, in loc. Weeks start
+// on Monday.
+func (i Interval) Truncate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	year, month, day := t.Date()
+	switch i {
+	case Hour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, loc)
+	case Week:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, loc)
+	case Month:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+}
+
+// Next returns the start of the bucket after the one starting at start.
+// Calendar arithmetic keeps days correct across DST changes.
+func (i Interval) Next(start time.Time) time.Time {
+	switch i {
+	case Hour:
+		return start.Add(time.Hour)
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Format returns a short label for a bucket starting at start.
+func (i Interval) Format(start time.Time) string {
+	switch i {
+	case Hour:
+		return start.Format("2006-01-02 15h")
+	case Month:
+		return start.Format("2006-01")
+	default:
+		return start.Format(time.DateOnly)
+	}
+}
+
+type Point struct {
+	Time   time.Time
+	Amount int64
+}
+
+type Bucket struct {
+	Start  time.Time
+	Count  int64
+	Sum    int64
+	Avg    float64
+	Median float64
+	Max    int64
+}
+
+// BucketPoints groups points into consecutive buckets covering [from, to) in
+// loc. Buckets without donations are kept, so the series has no gaps.
+func BucketPoints(points []Point, interval Interval, loc *time.Location, from, to time.Time) []Bucket {
+	var buckets []Bucket
+	index := make(map[time.Time]int)
+	for start := interval.Truncate(from, loc); start.Before(to); start = interval.Next(start) {
+		index[start] = len(buckets)
+		buckets = append(buckets, Bucket{Start: start})
+	}
+
+	amounts := make([][]int64, len(buckets))
+	for _, p := range points {
+		i, ok := index[interval.Truncate(p.Time, loc)]
+		if !ok {
+			continue
+		}
+		amounts[i] = append(amounts[i], p.Amount)
+	}
+
+	for i := range buckets {
+		summarize(&buckets[i], amounts[i])
+	}
+	return buckets
+}
+
+// CountBuckets returns how many buckets BucketPoints would create, counting
+// stops at limit+1 so a huge range is rejected without walking it.
+func CountBuckets(interval Interval, loc *time.Location, from, to time.Time, limit int) int {
+	n := 0
+	for start := interval.Truncate(from, loc); start.Before(to) && n <= limit; start = interval.Next(start) {
+		n++
+	}
+	return n
+}
+
+func summarize(b *Bucket, amounts []int64) {
+	if len(amounts) == 0 {
+		return
+	}
+	slices.Sort(amounts)
+	b.Count = int64(len(amounts))
+	for _, a := range amounts {
+		b.Sum += a
+	}
+	b.Max = amounts[len(amounts)-1]
+	b.Avg = float64(b.Sum) / float64(b.Count)
+	mid := len(amounts) / 2
+	if len(amounts)%2 == 0 {
+		b.Median = float64(amounts[mid-1]+amounts[mid]) / 2
+	} else {
+		b.Median = float64(amounts[mid])
+	}
+}
@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountBuckets(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2025, 3, 29, 0, 0, 0, 0, loc)
+	tests := []struct {
+		name     string
+		interval Interval
+		from, to time.Time
+		limit    int
+		want     int
+	}{
+		{"days", Day, from, from.AddDate(0, 0, 7), 100, 7},
+		// the clocks change on 2025-03-30, that day has 23 hours
+		{"hours over DST", Hour, from, from.AddDate(0, 0, 2), 100, 47},
+		{"at the limit", Day, from, from.AddDate(0, 0, 100), 100, 100},
+		{"over the limit", Day, from, from.AddDate(0, 0, 101), 100, 101},
+		{"empty", Day, from, from, 100, 0},
+		// would be about 17M hours without the limit
+		{"huge", Hour, time.Date(1, 1, 1, 0, 0, 0, 0, loc), from, 100, 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountBuckets(tt.interval, loc, tt.from, tt.to, tt.limit); got != tt.want {
+				t.Errorf("CountBuckets = %d, want %d", got, tt.want)
+			}
+			if tt.want <= tt.limit {
+				if got := len(BucketPoints(nil, tt.interval, loc, tt.from, tt.to)); got != tt.want {
+					t.Errorf("BucketPoints created %d buckets, want %d", got, tt.want)
+				}
+			}
+		})
+	}
+}
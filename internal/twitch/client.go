@@ -4,29 +4,52 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	ircServer = "irc.chat.twitch.tv:6667"
+	// MaxStreamers is how many channels one client joins at most.
+	MaxStreamers = 50
+)
+
+// Listen waits minReconnectDelay before reconnecting and doubles the wait
+// after every failed attempt up to maxReconnectDelay. Variables so tests can
+// shorten them.
+var (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
 )
 
 var (
 	ErrTooMuchStreamers = errors.New("Client can handle only 50 streamers")
+	// ErrLoginFailed is returned by Listen when Twitch rejects the login,
+	// reconnecting would not help.
+	ErrLoginFailed = errors.New("login authentication failed")
 )
 
 type Client struct {
+	addr          string
 	oauth         string
 	nick          string
 	streamers     []string
 	authenticated bool
 
+	// mu guards streamers and conn, which can be changed by Join and Part
+	// while Listen is running.
+	mu sync.Mutex
+
 	conn net.Conn
 
+	// done is closed by Close to stop Listen.
+	done      chan struct{}
+	closeOnce sync.Once
+
 	reader *bufio.Reader
 
 	onChatMessage func(m *MessagePrivate)
@@ -38,13 +61,19 @@ type Client struct {
 	onAnyMessage func(m Message)
 
 	onUnknowMessage func(m *UnknowMessage)
+
+	onConnect func()
+
+	onDisconnect func(err error)
 }
 
 func NewClient(oauth, nick string, streamers ...string) *Client {
 	return &Client{
+		addr:      ircServer,
 		oauth:     oauth,
 		nick:      nick,
 		streamers: streamers,
+		done:      make(chan struct{}),
 	}
 }
 
@@ -72,21 +101,66 @@ func (c *Client) SetOnUnknowMessage(callback func(m *UnknowMessage)) {
 	c.onUnknowMessage = callback
 }
 
+// SetOnConnect sets a callback run every time Listen has connected and
+// joined the channels.
+func (c *Client) SetOnConnect(callback func()) {
+	c.onConnect = callback
+}
+
+// SetOnDisconnect sets a callback run every time the connection of Listen
+// drops or connecting fails, with the error it failed with.
+func (c *Client) SetOnDisconnect(callback func(err error)) {
+	c.onDisconnect = callback
+}
+
+// Listen connects, joins the channels and handles messages until Close is
+// called. A dropped connection or a failed connect is reported to the
+// disconnect callback and retried with backoff, only a rejected login is
+// returned.
 func (c *Client) Listen() error {
+	delay := minReconnectDelay
 	for {
-		if err := c.connectAndJoin(); err != nil {
-			return fmt.Errorf("failed while connecting: %w", err)
-		}
-		for {
-			line, err := c.reader.ReadString('\n')
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
-				return fmt.Errorf("failed to read from connection: %w", err)
+		err := c.connectAndJoin()
+		if err == nil {
+			delay = minReconnectDelay
+			if c.onConnect != nil {
+				c.onConnect()
 			}
-			c.handleLine(line)
+			err = c.readLines()
+		}
+		c.closeConn()
+		select {
+		case <-c.done:
+			return nil
+		default:
 		}
+		if errors.Is(err, ErrLoginFailed) {
+			return err
+		}
+		if c.onDisconnect != nil {
+			c.onDisconnect(err)
+		}
+		log.Printf("Twitch connection lost (%v), reconnecting in %s", err, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.done:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// readLines handles the lines of the current connection until it fails.
+func (c *Client) readLines() error {
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read from connection: %w", err)
+		}
+		c.handleLine(line)
 	}
 }
 
@@ -117,14 +191,25 @@ func (c *Client) handleLine(line string) {
 	}
 }
 
+// Close stops Listen and closes the connection.
 func (c *Client) Close() {
-	c.conn.Close()
+	c.closeOnce.Do(func() { close(c.done) })
+	c.closeConn()
 }
 
-func (c *Client) connectAndJoin() error {
-	if len(c.streamers) == 0 {
-		return errors.New("nothing to listen (c.streamers is empty)")
+func (c *Client) closeConn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
 	}
+	c.authenticated = false
+}
+
+// connectAndJoin connects even without streamers, the connection then stays
+// idle until Join adds a channel, e.g. after a reload parted all of them.
+func (c *Client) connectAndJoin() error {
 	if err := c.makeConnection(); err != nil {
 		return fmt.Errorf("failed to connect to Twitch IRC: %w", err)
 	}
@@ -136,20 +221,36 @@ func (c *Client) connectAndJoin() error {
 }
 
 func (c *Client) makeConnection() error {
-	conn, err := net.Dial("tcp", ircServer)
+	conn, err := net.Dial("tcp", c.addr)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Twitch IRC: %w", err)
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		// Close ran while dialing
+		conn.Close()
+		return net.ErrClosed
+	default:
+	}
 	c.conn = conn
+	c.authenticated = false
 	c.reader = bufio.NewReader(conn)
 
 	return nil
 }
 
 func (c *Client) authenticate() error {
-	c.conn.SetDeadline(time.Now().Add(30 * time.Second))
-	defer c.conn.SetDeadline(time.Time{})
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return net.ErrClosed
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	defer conn.SetDeadline(time.Time{})
 
 	fmt.Fprintf(c, "PASS %s\r\n", c.oauth)
 	fmt.Fprintf(c, "NICK %s\r\n", c.nick)
@@ -164,42 +265,112 @@ func (c *Client) authenticate() error {
 		}
 		line = strings.TrimSpace(line)
 		if strings.Contains(line, "Login authentication failed") {
-			return errors.New("login authentication failed")
+			return ErrLoginFailed
 		} else if strings.Contains(line, "001 "+c.nick) {
 			log.Println("✅ Authentication successful! Connected to Twitch IRC.")
+			c.mu.Lock()
 			c.authenticated = true
+			c.mu.Unlock()
 			return nil
 		}
 	}
 }
 
 func (c *Client) makeJoins() {
-	for _, streamer := range c.streamers {
+	c.mu.Lock()
+	streamers := slices.Clone(c.streamers)
+	c.mu.Unlock()
+	for _, streamer := range streamers {
 		fmt.Fprintf(c, "JOIN %s\r\n", streamer)
 	}
 }
 
 func (c *Client) SetStreamers(s ...string) error {
-	if len(s) > 50 {
+	if len(s) > MaxStreamers {
 		return ErrTooMuchStreamers
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.streamers = s
 	return nil
 }
 
 func (c *Client) AddStreamers(s ...string) error {
-	if len(s)+len(c.streamers) > 50 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(s)+len(c.streamers) > MaxStreamers {
 		return ErrTooMuchStreamers
 	}
 	c.streamers = append(c.streamers, s...)
 	return nil
 }
 
+// Join adds streamers to the client and joins their channels right away when
+// the client is already connected. Channels that are already joined are skipped.
+func (c *Client) Join(s ...string) error {
+	c.mu.Lock()
+	var toJoin []string
+	for _, streamer := range s {
+		if !slices.Contains(c.streamers, streamer) && !slices.Contains(toJoin, streamer) {
+			toJoin = append(toJoin, streamer)
+		}
+	}
+	if len(toJoin)+len(c.streamers) > MaxStreamers {
+		c.mu.Unlock()
+		return ErrTooMuchStreamers
+	}
+	c.streamers = append(c.streamers, toJoin...)
+	connected := c.conn != nil && c.authenticated
+	c.mu.Unlock()
+
+	if !connected {
+		return nil
+	}
+	for _, streamer := range toJoin {
+		if _, err := fmt.Fprintf(c, "JOIN %s\r\n", streamer); err != nil {
+			return fmt.Errorf("failed to join %s: %w", streamer, err)
+		}
+	}
+	return nil
+}
+
+// Part removes streamers from the client and leaves their channels when the
+// client is connected, so they are not joined again after a reconnect.
+func (c *Client) Part(s ...string) error {
+	c.mu.Lock()
+	var toPart []string
+	c.streamers = slices.DeleteFunc(c.streamers, func(streamer string) bool {
+		if slices.Contains(s, streamer) {
+			toPart = append(toPart, streamer)
+			return true
+		}
+		return false
+	})
+	connected := c.conn != nil && c.authenticated
+	c.mu.Unlock()
+
+	if !connected {
+		return nil
+	}
+	for _, streamer := range toPart {
+		if _, err := fmt.Fprintf(c, "PART %s\r\n", streamer); err != nil {
+			return fmt.Errorf("failed to part %s: %w", streamer, err)
+		}
+	}
+	return nil
+}
+
 func (c *Client) SendPong(rawPing string) {
 	pong := strings.Replace(rawPing, "PING", "PONG", 1)
 	fmt.Fprintf(c, "%s\r\n", pong)
 }
 
 func (c *Client) Write(b []byte) (n int, err error) {
-	return c.conn.Write(b)
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return 0, net.ErrClosed
+	}
+	return conn.Write(b)
 }
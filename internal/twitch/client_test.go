@@ -0,0 +1,190 @@
+package twitch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIRC accepts connections on a local port, each is served by the next
+// handler. lines gets every line the client sends.
+type fakeIRC struct {
+	ln    net.Listener
+	lines chan string
+}
+
+type ircConn struct {
+	net.Conn
+	r     *bufio.Reader
+	lines chan<- string
+}
+
+func (c *ircConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err == nil {
+		line = strings.TrimSpace(line)
+		c.lines <- line
+	}
+	return line, err
+}
+
+func newFakeIRC(t *testing.T, handlers ...func(c *ircConn)) *fakeIRC {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeIRC{ln: ln, lines: make(chan string, 100)}
+	go func() {
+		for _, handle := range handlers {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(&ircConn{Conn: conn, r: bufio.NewReader(conn), lines: f.lines})
+			}()
+		}
+	}()
+	return f
+}
+
+// welcome reads the login of the client and accepts it.
+func welcome(c *ircConn) {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return
+		}
+		if nick, ok := strings.CutPrefix(line, "NICK "); ok {
+			fmt.Fprintf(c, ":tmi.twitch.tv 001 %s :Welcome, GLHF!\r\n", nick)
+			return
+		}
+	}
+}
+
+// drain keeps reading what the client sends until it disconnects.
+func drain(c *ircConn) {
+	for {
+		if _, err := c.readLine(); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeIRC) waitLine(t *testing.T, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-f.lines:
+			if line == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("client never sent %q", want)
+		}
+	}
+}
+
+func shortenReconnect(t *testing.T) {
+	minDelay, maxDelay := minReconnectDelay, maxReconnectDelay
+	minReconnectDelay, maxReconnectDelay = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { minReconnectDelay, maxReconnectDelay = minDelay, maxDelay })
+}
+
+func TestListenReconnects(t *testing.T) {
+	shortenReconnect(t)
+	messages := make(chan *MessagePrivate, 1)
+	f := newFakeIRC(t,
+		// connects and drops
+		welcome,
+		// fails before the login is accepted
+		func(c *ircConn) {},
+		// stays up
+		func(c *ircConn) {
+			welcome(c)
+			fmt.Fprint(c, ":bot!bot@bot.tmi.twitch.tv PRIVMSG #tartancz :sent 100\r\n")
+			drain(c)
+		},
+	)
+
+	c := NewAnonymousClient("#tartancz")
+	c.addr = f.ln.Addr().String()
+	connects := make(chan struct{}, 10)
+	var disconnects []error
+	c.SetOnConnect(func() { connects <- struct{}{} })
+	c.SetOnDisconnect(func(err error) { disconnects = append(disconnects, err) })
+	c.SetOnChatMessage(func(m *MessagePrivate) { messages <- m })
+
+	done := make(chan error, 1)
+	go func() { done <- c.Listen() }()
+
+	select {
+	case m := <-messages:
+		if m.Text != "sent 100" {
+			t.Errorf("message text = %q", m.Text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message after reconnecting")
+	}
+	c.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Listen() = %v after Close", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return after Close")
+	}
+	if len(connects) != 2 {
+		t.Errorf("connected %d times, want 2", len(connects))
+	}
+	if len(disconnects) != 2 {
+		t.Errorf("disconnect callback ran %d times, want 2: %v", len(disconnects), disconnects)
+	}
+}
+
+func TestListenWithoutChannels(t *testing.T) {
+	f := newFakeIRC(t, func(c *ircConn) {
+		welcome(c)
+		drain(c)
+	})
+	c := NewAnonymousClient()
+	c.addr = f.ln.Addr().String()
+	connected := make(chan struct{})
+	c.SetOnConnect(func() { close(connected) })
+	done := make(chan error, 1)
+	go func() { done <- c.Listen() }()
+	t.Cleanup(c.Close)
+
+	select {
+	case <-connected:
+	case err := <-done:
+		t.Fatalf("Listen() = %v without channels", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("never connected")
+	}
+	if err := c.Join("#tartancz"); err != nil {
+		t.Fatal(err)
+	}
+	f.waitLine(t, "JOIN #tartancz")
+}
+
+func TestListenLoginFailed(t *testing.T) {
+	f := newFakeIRC(t, func(c *ircConn) {
+		fmt.Fprint(c, ":tmi.twitch.tv NOTICE * :Login authentication failed\r\n")
+		drain(c)
+	})
+	c := NewClient("oauth:wrong", "someone", "#tartancz")
+	c.addr = f.ln.Addr().String()
+	if err := c.Listen(); !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("Listen() = %v, want ErrLoginFailed", err)
+	}
+}
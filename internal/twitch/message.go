@@ -131,3 +131,8 @@ func parseMessage(line string) Message {
 	}
 
 }
+
+// ParseMessage parses a single raw IRC line the same way the client does.
+func ParseMessage(line string) Message {
+	return parseMessage(line)
+}
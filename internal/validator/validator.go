@@ -1,6 +1,8 @@
 package validator
 
 import (
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -37,8 +39,13 @@ func (v *Validator) Error() string {
 	b := strings.Builder{}
 	if len(v.FieldErrors) > 0 {
 		b.WriteString("Field Errors:\n")
-		for k, v := range v.FieldErrors {
-			b.WriteString(k + ": " + v + "\n")
+		keys := make([]string, 0, len(v.FieldErrors))
+		for k := range v.FieldErrors {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			b.WriteString(k + ": " + v.FieldErrors[k] + "\n")
 		}
 	}
 	if len(v.NonFieldErrors) > 0 {
@@ -50,23 +57,79 @@ func (v *Validator) Error() string {
 	return b.String()
 }
 
-func HandleDateRange(validator *Validator, from, to string, fromTime *time.Time, toTime *time.Time) {
+// HandleDateRange parses from and to in loc into the half-open range
+// [fromTime, toTime). Dates are inclusive, so "-to 2025-06-12" still covers
+// that whole day. from can also be a relative range (see RelativeRange), in
+// which case to is optional and overrides only the end of the range.
+func HandleDateRange(validator *Validator, from, to string, loc *time.Location, fromTime *time.Time, toTime *time.Time) {
+	now := time.Now().In(loc)
+	*toTime = startOfDay(now).AddDate(0, 0, 1)
+
 	if from == "" {
 		*fromTime = time.Time{}
+	} else if relFrom, relTo, ok := RelativeRange(from, now); ok {
+		*fromTime, *toTime = relFrom, relTo
 	} else {
-		validator.CheckField(ValidAndConvertDateTime(from, time.DateOnly, fromTime), "from", "Invalid from date format. Use 'YYYY-MM-DD' format.")
+		validator.CheckField(ValidAndConvertDateTimeIn(from, time.DateOnly, loc, fromTime), "from", "Invalid from date. Use 'YYYY-MM-DD' or "+RelativeRangeHelp+".")
 	}
 
-	if to == "" {
-		*toTime = time.Now()
-	} else {
-		validator.CheckField(ValidAndConvertDateTime(to, time.DateOnly, toTime), "to", "Invalid to date format. Use 'YYYY-MM-DD' format.")
+	if to != "" {
+		ok := ValidAndConvertDateTimeIn(to, time.DateOnly, loc, toTime)
+		validator.CheckField(ok, "to", "Invalid to date format. Use 'YYYY-MM-DD' format.")
+		if ok {
+			*toTime = toTime.AddDate(0, 0, 1)
+		}
 	}
 	validator.CheckField(fromTime.Before(*toTime), "from", "From date must be before To date.")
 }
 
+// RelativeRangeHelp lists the relative ranges understood by RelativeRange.
+const RelativeRangeHelp = "today, yesterday, Nd (e.g. 7d), this-week, last-week, this-month or last-month"
+
+// RelativeRange resolves a named range relative to now, in now's location.
+// The returned range is half-open [from, to), names are case-insensitive.
+func RelativeRange(name string, now time.Time) (from, to time.Time, ok bool) {
+	name = strings.ToLower(name)
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	monthStart := today.AddDate(0, 0, 1-today.Day())
+
+	switch name {
+	case "today":
+		return today, tomorrow, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, true
+	case "this-week":
+		return weekStart, tomorrow, true
+	case "last-week":
+		return weekStart.AddDate(0, 0, -7), weekStart, true
+	case "this-month":
+		return monthStart, tomorrow, true
+	case "last-month":
+		return monthStart.AddDate(0, -1, 0), monthStart, true
+	}
+
+	if days, found := strings.CutSuffix(name, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err == nil && n > 0 {
+			return today.AddDate(0, 0, 1-n), tomorrow, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
 func ValidAndConvertDateTime(date string, format string, t *time.Time) bool {
-	parsed, err := time.Parse(format, date)
+	return ValidAndConvertDateTimeIn(date, format, time.UTC, t)
+}
+
+func ValidAndConvertDateTimeIn(date string, format string, loc *time.Location, t *time.Time) bool {
+	parsed, err := time.ParseInLocation(format, date, loc)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,332 @@
+package webhook
+
+import (
+	"TwitchDonoCalculator/internal/db"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Values of webhook_delivery.status.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// StatusDead deliveries ran out of attempts and wait for a manual
+	// redelivery.
+	StatusDead = "dead"
+)
+
+// EventDonation is sent for every new donation.
+const EventDonation = "donation"
+
+// Headers sent with every delivery. The signature is "sha256=" followed by
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is dead.
+	MaxAttempts = 10
+	// the first retry waits baseDelay, every next one twice as long
+	baseDelay     = 30 * time.Second
+	maxDelay      = 6 * time.Hour
+	batchSize     = 50
+	sendTimeout   = 10 * time.Second
+	maxErrorBytes = 200
+)
+
+// Target is a configured webhook.
+type Target struct {
+	Name   string
+	URL    string
+	Secret string
+	// Channels limits the target to these channels, empty means all.
+	Channels []string
+}
+
+func (t Target) matches(channel string) bool {
+	return len(t.Channels) == 0 || slices.Contains(t.Channels, channel)
+}
+
+// Payload is the JSON body of every delivery.
+type Payload struct {
+	Event     string    `json:"event"`
+	Channel   string    `json:"channel"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the signature header value of body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature was made by Sign with the same secret,
+// receivers should also reject old timestamps.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Outbox stores every event per matching target in the webhook_delivery
+// table and delivers it from there, so deliveries survive restarts and are
+// retried with exponential backoff. A delivery may be sent more than once,
+// receivers can deduplicate by the HeaderDelivery id.
+type Outbox struct {
+	q       *db.Queries
+	client  *http.Client
+	targets map[string]Target
+	onDead  func(db.WebhookDelivery)
+	now     func() time.Time
+	wake    chan struct{}
+}
+
+// NewOutbox returns an outbox delivering to targets. onDead is called when a
+// delivery runs out of attempts and may be nil.
+func NewOutbox(q *db.Queries, targets []Target, client *http.Client, onDead func(db.WebhookDelivery)) *Outbox {
+	o := &Outbox{
+		q:       q,
+		client:  client,
+		targets: make(map[string]Target, len(targets)),
+		onDead:  onDead,
+		now:     time.Now,
+		wake:    make(chan struct{}, 1),
+	}
+	for _, t := range targets {
+		o.targets[t.Name] = t
+	}
+	return o
+}
+
+// Targets returns the configured targets sorted by name.
+func (o *Outbox) Targets() []Target {
+	targets := make([]Target, 0, len(o.targets))
+	for _, t := range o.targets {
+		targets = append(targets, t)
+	}
+	slices.SortFunc(targets, func(a, b Target) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return targets
+}
+
+// Enqueue stores event for every target of channel and wakes up Run.
+func (o *Outbox) Enqueue(ctx context.Context, channel, event string, data any) error {
+	now := o.now().UTC()
+	body, err := json.Marshal(Payload{Event: event, Channel: channel, CreatedAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+	queued := false
+	for _, t := range o.targets {
+		if !t.matches(channel) {
+			continue
+		}
+		_, err := o.q.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			Webhook:       t.Name,
+			Channel:       channel,
+			Event:         event,
+			Payload:       string(body),
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		queued = true
+	}
+	if queued {
+		o.notify()
+	}
+	return nil
+}
+
+// Redeliver queues a delivered or dead delivery again with a fresh set of
+// attempts. It returns false when the delivery is already pending or does
+// not exist.
+func (o *Outbox) Redeliver(ctx context.Context, id int64) (bool, error) {
+	n, err := o.q.RedeliverWebhookDelivery(ctx, db.RedeliverWebhookDeliveryParams{
+		NextAttemptAt: o.now().UTC(),
+		ID:            id,
+	})
+	if err != nil || n == 0 {
+		return false, err
+	}
+	o.notify()
+	return true, nil
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries whenever something is enqueued and at least every
+// interval, until ctx is done. Errors are logged.
+func (o *Outbox) Run(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := o.Flush(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("failed to send webhooks", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Flush sends every delivery that is due now. Targets are sent to
+// concurrently, so a slow one doesn't hold up the others, and the
+// deliveries of one target in order. Failed sends are scheduled for a retry,
+// only database errors are returned.
+func (o *Outbox) Flush(ctx context.Context) error {
+	for {
+		due, err := o.q.ListDueWebhookDeliveries(ctx, db.ListDueWebhookDeliveriesParams{
+			Now:      o.now().UTC(),
+			RowLimit: batchSize,
+		})
+		if err != nil {
+			return err
+		}
+		byTarget := make(map[string][]db.WebhookDelivery)
+		for _, d := range due {
+			byTarget[d.Webhook] = append(byTarget[d.Webhook], d)
+		}
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+		)
+		for _, deliveries := range byTarget {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, d := range deliveries {
+					if err := o.deliver(ctx, d); err != nil {
+						mu.Lock()
+						errs = append(errs, err)
+						mu.Unlock()
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// deliver sends d once and records the outcome.
+func (o *Outbox) deliver(ctx context.Context, d db.WebhookDelivery) error {
+	statusCode, sendErr := o.send(ctx, d)
+	now := o.now().UTC()
+	if sendErr == nil {
+		return o.q.MarkWebhookDelivered(ctx, db.MarkWebhookDeliveredParams{
+			LastStatusCode: int64(statusCode),
+			DeliveredAt:    sql.NullTime{Time: now, Valid: true},
+			ID:             d.ID,
+		})
+	}
+	if ctx.Err() != nil {
+		// shutting down, the delivery stays due and is sent after a restart
+		return ctx.Err()
+	}
+
+	d.Attempts++
+	d.Status = StatusPending
+	d.NextAttemptAt = now.Add(Backoff(int(d.Attempts)))
+	if d.Attempts >= MaxAttempts || errors.Is(sendErr, errUnknownTarget) {
+		d.Status = StatusDead
+	}
+	d.LastStatusCode = int64(statusCode)
+	d.LastError = sendErr.Error()
+	err := o.q.MarkWebhookFailed(ctx, db.MarkWebhookFailedParams{
+		Status:         d.Status,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		ID:             d.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if d.Status == StatusDead && o.onDead != nil {
+		o.onDead(d)
+	}
+	return nil
+}
+
+var errUnknownTarget = errors.New("webhook is no longer configured")
+
+func (o *Outbox) send(ctx context.Context, d db.WebhookDelivery) (int, error) {
+	t, ok := o.targets[d.Webhook]
+	if !ok {
+		return 0, errUnknownTarget
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := o.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TwitchDonoCalculator-Webhook")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(t.Secret, timestamp, body))
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		return resp.StatusCode, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// Backoff is how long to wait after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,267 @@
+package webhook
+
+import (
+	"TwitchDonoCalculator/internal/config"
+	"TwitchDonoCalculator/internal/db"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+// receiver is an httptest webhook receiver that verifies every request and
+// answers with status.
+type receiver struct {
+	*httptest.Server
+	t        *testing.T
+	status   atomic.Int64
+	requests atomic.Int64
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	r := &receiver{t: t}
+	r.status.Store(int64(status))
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+		if err != nil {
+			t.Errorf("invalid timestamp header: %v", err)
+		}
+		if !Verify(testSecret, timestamp, body, req.Header.Get(HeaderSignature)) {
+			t.Errorf("invalid signature %q", req.Header.Get(HeaderSignature))
+		}
+		if req.Header.Get(HeaderEvent) != EventDonation || req.Header.Get(HeaderDelivery) == "" {
+			t.Errorf("missing event or delivery header: %v", req.Header)
+		}
+		w.WriteHeader(int(r.status.Load()))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// newTestOutbox returns an outbox on a temporary database with its clock
+// at the returned time.
+func newTestOutbox(t *testing.T, targets []Target, onDead func(db.WebhookDelivery)) (*Outbox, *time.Time) {
+	t.Helper()
+	cfg := config.Default().DB
+	cfg.DSN = filepath.Join(t.TempDir(), "db.db")
+	database, err := db.OpenDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	db.RunMigrations(database)
+
+	now := time.Date(2026, 6, 12, 12, 0, 0, 0, time.UTC)
+	o := NewOutbox(db.New(database), targets, &http.Client{}, onDead)
+	o.now = func() time.Time { return now }
+	return o, &now
+}
+
+// enqueueOne enqueues a donation and returns its only delivery.
+func enqueueOne(t *testing.T, o *Outbox) db.WebhookDelivery {
+	t.Helper()
+	ctx := context.Background()
+	if err := o.Enqueue(ctx, "#tartancz", EventDonation, map[string]any{"amount": 500}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := o.q.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{RowLimit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func getDelivery(t *testing.T, o *Outbox, id int64) db.WebhookDelivery {
+	t.Helper()
+	d, err := o.q.GetWebhookDelivery(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"donation"}`)
+	signature := Sign(testSecret, 1700000000, body)
+	if !Verify(testSecret, 1700000000, body, signature) {
+		t.Error("signature does not verify")
+	}
+	if Verify(testSecret, 1700000001, body, signature) {
+		t.Error("signature verifies with another timestamp")
+	}
+	if Verify(testSecret, 1700000000, []byte(`{"event":"other"}`), signature) {
+		t.Error("signature verifies with another body")
+	}
+	if Verify("another-secret-value", 1700000000, body, signature) {
+		t.Error("signature verifies with another secret")
+	}
+}
+
+func TestOutboxDelivered(t *testing.T) {
+	rcv := newReceiver(t, http.StatusNoContent)
+	o, _ := newTestOutbox(t, []Target{
+		{Name: "sheet", URL: rcv.URL, Secret: testSecret},
+		{Name: "other", URL: rcv.URL, Secret: testSecret, Channels: []string{"#other"}},
+	}, nil)
+	d := enqueueOne(t, o)
+	if err := o.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	d = getDelivery(t, o, d.ID)
+	if d.Status != StatusDelivered || !d.DeliveredAt.Valid || d.LastStatusCode != http.StatusNoContent {
+		t.Errorf("delivery %+v, want delivered with 204", d)
+	}
+	if n := rcv.requests.Load(); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+func TestOutboxRetry(t *testing.T) {
+	rcv := newReceiver(t, http.StatusInternalServerError)
+	o, now := newTestOutbox(t, []Target{{Name: "sheet", URL: rcv.URL, Secret: testSecret}}, nil)
+	d := enqueueOne(t, o)
+	ctx := context.Background()
+	if err := o.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	d = getDelivery(t, o, d.ID)
+	if d.Status != StatusPending || d.Attempts != 1 || d.LastStatusCode != http.StatusInternalServerError || d.LastError == "" {
+		t.Fatalf("delivery %+v, want a pending retry after a 500", d)
+	}
+	if want := now.Add(Backoff(1)); !d.NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt at %v, want %v", d.NextAttemptAt, want)
+	}
+
+	// not due yet
+	*now = now.Add(Backoff(1) - time.Second)
+	if err := o.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := rcv.requests.Load(); n != 1 {
+		t.Fatalf("%d requests before the retry is due, want 1", n)
+	}
+
+	*now = now.Add(time.Second)
+	if err := o.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	d = getDelivery(t, o, d.ID)
+	if d.Attempts != 2 || !d.NextAttemptAt.Equal(now.Add(Backoff(2))) {
+		t.Errorf("delivery %+v, want the second attempt backed off by %v", d, Backoff(2))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		10: 4*time.Hour + 16*time.Minute,
+		20: 6 * time.Hour,
+	}
+	for attempts, want := range tests {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestOutboxDeadAndRedeliver(t *testing.T) {
+	rcv := newReceiver(t, http.StatusServiceUnavailable)
+	var dead []db.WebhookDelivery
+	o, now := newTestOutbox(t, []Target{{Name: "sheet", URL: rcv.URL, Secret: testSecret}}, func(d db.WebhookDelivery) {
+		dead = append(dead, d)
+	})
+	d := enqueueOne(t, o)
+	ctx := context.Background()
+	for range MaxAttempts {
+		if err := o.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(maxDelay)
+	}
+	d = getDelivery(t, o, d.ID)
+	if d.Status != StatusDead || d.Attempts != MaxAttempts {
+		t.Fatalf("delivery %+v, want dead after %d attempts", d, MaxAttempts)
+	}
+	if len(dead) != 1 || dead[0].ID != d.ID {
+		t.Fatalf("onDead called with %+v, want once with the delivery", dead)
+	}
+	if err := o.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := rcv.requests.Load(); n != MaxAttempts {
+		t.Errorf("%d requests, want %d", n, MaxAttempts)
+	}
+
+	ok, err := o.Redeliver(ctx, d.ID)
+	if err != nil || !ok {
+		t.Fatalf("Redeliver = %v, %v", ok, err)
+	}
+	d = getDelivery(t, o, d.ID)
+	if d.Status != StatusPending || d.Attempts != 0 || !d.NextAttemptAt.Equal(*now) {
+		t.Errorf("redelivered %+v, want pending with no attempts", d)
+	}
+	if ok, err := o.Redeliver(ctx, d.ID); err != nil || ok {
+		t.Errorf("Redeliver of a pending delivery = %v, %v, want false", ok, err)
+	}
+
+	rcv.status.Store(http.StatusOK)
+	if err := o.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d = getDelivery(t, o, d.ID); d.Status != StatusDelivered {
+		t.Errorf("delivery %+v, want delivered after the redelivery", d)
+	}
+}
+
+func TestFlushSlowTarget(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { once.Do(func() { close(release) }) })
+	fastDone := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fastDone <- struct{}{}
+	}))
+	t.Cleanup(fast.Close)
+
+	o, _ := newTestOutbox(t, []Target{
+		{Name: "slow", URL: slow.URL, Secret: testSecret},
+		{Name: "fast", URL: fast.URL, Secret: testSecret},
+	}, nil)
+	if err := o.Enqueue(context.Background(), "#tartancz", EventDonation, nil); err != nil {
+		t.Fatal(err)
+	}
+	flushed := make(chan error, 1)
+	go func() { flushed <- o.Flush(context.Background()) }()
+
+	select {
+	case <-fastDone:
+	case <-time.After(sendTimeout / 2):
+		t.Fatal("the fast target waited for the slow one")
+	}
+	once.Do(func() { close(release) })
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+}
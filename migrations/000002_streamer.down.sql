@@ -0,0 +1,2 @@
+DROP TABLE streamer_history;
+DROP TABLE streamer;
@@ -0,0 +1,29 @@
+CREATE TABLE streamer (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel TEXT NOT NULL UNIQUE,
+    bot_name TEXT NOT NULL,
+    value_regex TEXT NOT NULL,
+    line_filter_contain TEXT NOT NULL DEFAULT '',
+    log_message BOOLEAN NOT NULL DEFAULT 0,
+    notify_threshold INTEGER NOT NULL DEFAULT 10000,
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    updated_by TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE streamer_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    streamer_id INTEGER NOT NULL REFERENCES streamer(id),
+    channel TEXT NOT NULL,
+    bot_name TEXT NOT NULL,
+    value_regex TEXT NOT NULL,
+    line_filter_contain TEXT NOT NULL,
+    log_message BOOLEAN NOT NULL,
+    notify_threshold INTEGER NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    changed_by TEXT NOT NULL,
+    changed_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_streamer_history_streamer_id ON streamer_history(streamer_id);
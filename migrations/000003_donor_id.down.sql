@@ -0,0 +1 @@
+ALTER TABLE donation DROP COLUMN donor_id;
@@ -0,0 +1 @@
+ALTER TABLE donation ADD COLUMN donor_id TEXT;
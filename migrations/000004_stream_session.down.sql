@@ -0,0 +1,3 @@
+DROP INDEX idx_donation_session_id;
+ALTER TABLE donation DROP COLUMN session_id;
+DROP TABLE stream_session;
@@ -0,0 +1,14 @@
+CREATE TABLE stream_session (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel TEXT NOT NULL,
+    source TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    last_activity_at DATETIME NOT NULL,
+    ended_at DATETIME
+);
+
+CREATE INDEX idx_stream_session_channel_started_at ON stream_session(channel, started_at);
+
+ALTER TABLE donation ADD COLUMN session_id INTEGER REFERENCES stream_session(id);
+
+CREATE INDEX idx_donation_session_id ON donation(session_id);
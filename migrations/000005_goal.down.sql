@@ -0,0 +1,2 @@
+DROP INDEX idx_goal_channel;
+DROP TABLE goal;
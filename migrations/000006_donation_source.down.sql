@@ -0,0 +1,2 @@
+DROP INDEX idx_donation_channel_amount;
+ALTER TABLE donation DROP COLUMN source;
@@ -0,0 +1,3 @@
+ALTER TABLE donation ADD COLUMN source TEXT NOT NULL DEFAULT 'live';
+
+CREATE INDEX idx_donation_channel_amount ON donation(channel, amount);
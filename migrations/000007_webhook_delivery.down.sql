@@ -0,0 +1,2 @@
+DROP INDEX idx_webhook_delivery_due;
+DROP TABLE webhook_delivery;
@@ -0,0 +1,3 @@
+DROP INDEX idx_discord_audit_log_user;
+DROP TABLE discord_audit_log;
+DROP TABLE discord_permission;
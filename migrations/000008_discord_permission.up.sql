@@ -0,0 +1,24 @@
+CREATE TABLE discord_permission (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    level TEXT NOT NULL,
+    granted_by TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (kind, subject_id)
+);
+
+CREATE TABLE discord_audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    role_ids TEXT NOT NULL,
+    command TEXT NOT NULL,
+    line TEXT NOT NULL,
+    level TEXT NOT NULL,
+    required_level TEXT NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_discord_audit_log_user ON discord_audit_log(user_id, id);
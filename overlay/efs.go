@@ -0,0 +1,6 @@
+package overlay
+
+import "embed"
+
+//go:embed *.html
+var OverlayFiles embed.FS